@@ -3,21 +3,76 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 
 	"github.com/jorkle/jork/internal/app"
+	"github.com/jorkle/jork/internal/models"
+)
+
+// Set at build time, e.g. -ldflags "-X main.version=1.2.0 -X main.commit=abc1234"
+var (
+	version = "dev"
+	commit  = ""
 )
 
 func main() {
 	// Parse command-line options
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: jork [flags]\n\nPractise explaining things to an AI listener by text or voice.\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	showVersion := flag.Bool("version", false, "Print the version and exit")
 	claudeModel := flag.String("claude-model", "", "Specify the Anthropic AI model for responses")
+	noAltScreen := flag.Bool("no-altscreen", false, "Run without the alternate screen so output stays in scrollback")
+	audioInputFile := flag.String("audio-input-file", "", "Use the samples of this WAV file as every recording instead of the microphone")
+	setup := flag.Bool("setup", false, "Run the first-time setup wizard again")
+	selfTest := flag.Bool("selftest", false, "Check keys, recording, transcription, response, speech and playback, then exit")
+	testOutput := flag.Bool("test-output", false, "Play a test tone through the audio output, then exit")
+	prompt := flag.String("prompt", "", "Answer this prompt without the TUI, print the response and exit; piped stdin is read the same way")
+	mode := flag.String("mode", "", "Communication mode for --prompt: text-to-text, text-to-voice, voice-to-text or voice-to-voice")
+	level := flag.String("level", "", "Knowledge level for --prompt: child, highschool, freshman or coworker")
 	flag.Parse()
+	if *showVersion {
+		// Answered before the app is created, so it works without any API key
+		if commit != "" {
+			fmt.Printf("jork %s (%s)\n", version, commit)
+		} else {
+			fmt.Printf("jork %s\n", version)
+		}
+		return
+	}
 	if *claudeModel != "" {
 		os.Setenv("CLAUDE_MODEL", *claudeModel)
 	}
+	if *noAltScreen {
+		os.Setenv("JORK_NO_ALTSCREEN", "1")
+	}
+	if *setup {
+		os.Setenv("JORK_SETUP", "1")
+	}
+	if *audioInputFile != "" {
+		os.Setenv("JORK_AUDIO_INPUT_FILE", *audioInputFile)
+	}
+
+	// A prompt from the flag or a pipe is answered once without the TUI
+	headless := *prompt != ""
+	if !headless && !*selfTest && !*testOutput && stdinIsPipe() {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalf("Failed to read prompt from stdin: %v", err)
+		}
+		*prompt = string(input)
+		headless = true
+	}
+	if !headless && (*mode != "" || *level != "") {
+		fmt.Fprintln(os.Stderr, "--mode and --level only apply with --prompt or piped input")
+		os.Exit(2)
+	}
 
 	// Create the application
 	application, err := app.NewApp()
@@ -25,6 +80,46 @@ func main() {
 		log.Fatalf("Failed to create application: %v", err)
 	}
 
+	if headless {
+		err := runHeadless(application, *prompt, *mode, *level)
+		if cleanupErr := application.Cleanup(); cleanupErr != nil {
+			log.Printf("Error during cleanup: %v", cleanupErr)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Run the pipeline self-check without the TUI
+	if *selfTest {
+		err := application.SelfTest(os.Stdout)
+		if cleanupErr := application.Cleanup(); cleanupErr != nil {
+			log.Printf("Error during cleanup: %v", cleanupErr)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Self-test passed")
+		return
+	}
+
+	// Play the output test tone without the TUI
+	if *testOutput {
+		backend, err := application.TestOutput()
+		if cleanupErr := application.Cleanup(); cleanupErr != nil {
+			log.Printf("Error during cleanup: %v", cleanupErr)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Test tone played via %s\n", backend)
+		return
+	}
+
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -39,8 +134,11 @@ func main() {
 		os.Exit(0)
 	}()
 
-	// Run the application
-	if err := application.Run(); err != nil {
+	// Run the application, with log output kept off the screen the TUI draws
+	restoreLog := logToFile(application.ConfigDir())
+	err = application.Run()
+	restoreLog()
+	if err != nil {
 		log.Fatalf("Application error: %v", err)
 	}
 
@@ -49,3 +147,45 @@ func main() {
 		log.Printf("Error during cleanup: %v", err)
 	}
 }
+
+// runHeadless applies the one-shot mode and level, then answers prompt on stdout
+func runHeadless(application *app.App, prompt, mode, level string) error {
+	if mode != "" {
+		m, err := models.ParseCommunicationMode(mode)
+		if err != nil {
+			return err
+		}
+		application.SetMode(m)
+	}
+	if level != "" {
+		l, err := models.ParseKnowledgeLevel(level)
+		if err != nil {
+			return err
+		}
+		application.SetKnowledgeLevel(l)
+	}
+	return application.RunOnce(os.Stdout, prompt)
+}
+
+// logToFile sends log output to jork.log in dir and returns a function that closes it and
+// restores stderr. Logging stays on stderr if the file can't be opened.
+func logToFile(dir string) func() {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return func() {}
+	}
+	file, err := os.OpenFile(filepath.Join(dir, "jork.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return func() {}
+	}
+	log.SetOutput(file)
+	return func() {
+		log.SetOutput(os.Stderr)
+		file.Close()
+	}
+}
+
+// stdinIsPipe reports whether stdin is redirected from a pipe or file rather than a terminal
+func stdinIsPipe() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
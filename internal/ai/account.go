@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Account routes OpenAI requests to an organization and project; empty fields send no header.
+// BaseURL and Proxy point requests at an OpenAI-compatible gateway and through an HTTP proxy.
+type Account struct {
+	Organization string
+	Project      string
+	BaseURL      string // API root such as https://api.openai.com/v1, empty for OpenAI's
+	Proxy        string // HTTP proxy URL, empty to use the proxy environment variables
+}
+
+// ChatURL returns the chat completions endpoint under the account's API root, or "" for OpenAI's
+func (a Account) ChatURL() string {
+	if a.BaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(a.BaseURL, "/") + "/chat/completions"
+}
+
+// Transport returns the round tripper sending requests through the account's proxy,
+// or nil to use http.DefaultTransport
+func (a Account) Transport() http.RoundTripper {
+	if a.Proxy == "" {
+		return nil
+	}
+	proxy, err := url.Parse(a.Proxy)
+	if err != nil {
+		// Config validation rejects bad proxy URLs, so this only guards direct use
+		return nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	return transport
+}
+
+// setHeaders adds the account's routing headers to h
+func (a Account) setHeaders(h http.Header) {
+	if a.Organization != "" {
+		h.Set("OpenAI-Organization", a.Organization)
+	}
+	if a.Project != "" {
+		h.Set("OpenAI-Project", a.Project)
+	}
+}
+
+// newSDKClient creates a go-openai client whose requests carry the account headers
+func newSDKClient(apiKey string, account Account) *openai.Client {
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.OrgID = account.Organization
+	if account.BaseURL != "" {
+		cfg.BaseURL = strings.TrimSuffix(account.BaseURL, "/")
+	}
+	transport := account.Transport()
+	if account.Project != "" {
+		// The SDK has no project setting, so add the header in the transport
+		transport = &accountTransport{account: Account{Project: account.Project}, base: transport}
+	}
+	if transport != nil {
+		cfg.HTTPClient = &http.Client{Transport: transport}
+	}
+	return openai.NewClientWithConfig(cfg)
+}
+
+// accountTransport adds account headers to every request it sends
+type accountTransport struct {
+	account Account
+	base    http.RoundTripper // nil for http.DefaultTransport
+}
+
+func (t *accountTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	t.account.setHeaders(req.Header)
+	if t.base != nil {
+		return t.base.RoundTrip(req)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrCorruptAudio is returned when synthesized audio is empty or not a recognizable audio file
+var ErrCorruptAudio = errors.New("synthesized audio was corrupt")
+
+// validateAudioFile checks that path is non-empty and starts like an MP3, Ogg or WAV file
+func validateAudioFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := make([]byte, 12)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return fmt.Errorf("%w: file is empty", ErrCorruptAudio)
+		}
+		return err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("ID3")), bytes.HasPrefix(header, []byte("OggS")):
+		return nil
+	case len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0:
+		// MPEG frame sync
+		return nil
+	case len(header) == 12 && bytes.Equal(header[:4], []byte("RIFF")) && bytes.Equal(header[8:], []byte("WAVE")):
+		return nil
+	}
+	return fmt.Errorf("%w: unrecognized audio header", ErrCorruptAudio)
+}
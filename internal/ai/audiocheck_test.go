@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateAudioFile(t *testing.T) {
+	wav := []byte("RIFF\x24\x00\x00\x00WAVEfmt ")
+	tests := []struct {
+		name    string
+		data    []byte
+		corrupt bool
+	}{
+		{"mp3 with ID3 tag", []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), false},
+		{"mp3 frame", []byte{0xFF, 0xFB, 0x90, 0x64, 0x00}, false},
+		{"ogg", []byte("OggS\x00\x02"), false},
+		{"wav", wav, false},
+		{"empty", nil, true},
+		{"truncated ID3 tag", []byte("ID"), true},
+		{"truncated frame sync", []byte{0xFF}, true},
+		{"truncated wav header", wav[:8], true},
+		{"wav header cut inside WAVE", wav[:10], true},
+		{"not audio", []byte(`{"error": "bad gateway"}`), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "speech")
+			if err := os.WriteFile(path, tt.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			err := validateAudioFile(path)
+			if tt.corrupt && !errors.Is(err, ErrCorruptAudio) {
+				t.Errorf("error = %v, want ErrCorruptAudio", err)
+			}
+			if !tt.corrupt && err != nil {
+				t.Errorf("error = %v, want none", err)
+			}
+		})
+	}
+}
+
+func TestTextToSpeechRemovesTruncatedAudio(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte("RIFF\x24\x00")) // the download stopped partway through the header
+	}))
+	defer server.Close()
+
+	tts := NewTTSClient("test-key", "tts-1", "alloy")
+	tts.SetAccount(Account{BaseURL: server.URL})
+	path := filepath.Join(t.TempDir(), "speech.mp3")
+	err := tts.TextToSpeech("Hello", path)
+	if !errors.Is(err, ErrCorruptAudio) {
+		t.Errorf("error = %v, want ErrCorruptAudio", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("truncated audio was left at %s", path)
+	}
+}
+
+func TestTextToSpeechRemovesFailedDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("Content-Length", "4096")
+		w.Write([]byte("ID3\x04")) // the connection drops before the promised length arrives
+	}))
+	defer server.Close()
+
+	tts := NewTTSClient("test-key", "tts-1", "alloy")
+	tts.SetAccount(Account{BaseURL: server.URL})
+	path := filepath.Join(t.TempDir(), "speech.mp3")
+	if err := tts.TextToSpeech("Hello", path); err == nil {
+		t.Fatal("an interrupted download succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the partial download was left at %s", path)
+	}
+}
@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// anthropicVersion is the Anthropic API version requests are written against
+const anthropicVersion = "2023-06-01"
+
+// ClaudeClient sends chat requests to the Anthropic messages API. OpenAIClient hands it the
+// requests for Claude models, whose bodies chatRequestBody already writes in Anthropic's shape.
+type ClaudeClient struct {
+	APIKey  string
+	BaseURL string // messages endpoint
+	Version string // sent as the anthropic-version header
+}
+
+// NewClaudeClient creates a client for the Anthropic messages API
+func NewClaudeClient(apiKey string) *ClaudeClient {
+	return &ClaudeClient{
+		APIKey:  apiKey,
+		BaseURL: "https://api.anthropic.com/v1/messages",
+		Version: anthropicVersion,
+	}
+}
+
+// newRequest creates an authenticated POST of body to the messages endpoint
+func (c *ClaudeClient) newRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", c.Version)
+	return req, nil
+}
+
+// isClaudeModel reports whether model is served by Anthropic rather than an OpenAI-compatible API
+func isClaudeModel(model string) bool {
+	return strings.Contains(strings.ToLower(model), "claude")
+}
+
+// apiKeyFor returns the key requests for model are authenticated with
+func (c *OpenAIClient) apiKeyFor(model string) string {
+	if isClaudeModel(model) && c.Claude != nil {
+		return c.Claude.APIKey
+	}
+	return c.APIKey
+}
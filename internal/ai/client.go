@@ -2,26 +2,60 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/jorkle/jork/internal/models"
 )
 
+// ErrNoAPIKey is returned instead of sending a request without credentials
+var ErrNoAPIKey = errors.New("no API key configured")
+
+// ErrContextLengthExceeded is returned when the request doesn't fit the model's context window
+var ErrContextLengthExceeded = errors.New("context length exceeded")
+
 // OpenAIClient handles communication with the OpenAI API
 type OpenAIClient struct {
 	APIKey     string
 	Model      string
 	HTTPClient *http.Client
 	BaseURL    string
+	Debug      *DebugLogger
+	Prompt     PromptOptions
+	Fallbacks  []string // models tried in order when Model is unavailable
+	Account    Account
+	MaxRetries int // retries of a request failing with 429 or a transient 5xx
+	// Temperature is the sampling temperature sent with chat requests; zero uses the provider default
+	Temperature float64
+	// Claude serves models whose name contains "claude"; when nil they go to BaseURL too
+	Claude *ClaudeClient
+}
+
+// ChatResult is a chat reply along with the model that produced it and the tokens it used
+type ChatResult struct {
+	Text         string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+}
+
+// APIError is an error response from the chat endpoint
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
 }
 
-// NewClaudeClient creates a new Claude API client
+// NewOpenAIClient creates a new chat client for the OpenAI API
 func NewOpenAIClient(apiKey, model string) *OpenAIClient {
 	return &OpenAIClient{
 		APIKey:  apiKey,
@@ -41,72 +75,154 @@ func (c *OpenAIClient) GenerateResponse(
 	conversationHistory []models.ConversationEntry,
 	topic string,
 ) (string, error) {
+	result, err := c.GenerateResponseFrom(context.Background(), userInput, knowledgeLevel, mode, conversationHistory, topic)
+	return result.Text, err
+}
+
+// GenerateResponseFrom is GenerateResponse that also reports the model that answered,
+// which is a fallback model when the primary was unavailable, and the tokens used.
+// Cancelling ctx aborts the request.
+func (c *OpenAIClient) GenerateResponseFrom(
+	ctx context.Context,
+	userInput string,
+	knowledgeLevel models.KnowledgeLevel,
+	mode models.CommunicationMode,
+	conversationHistory []models.ConversationEntry,
+	topic string,
+) (ChatResult, error) {
+	messages := c.buildChatMessages(userInput, knowledgeLevel, mode, conversationHistory, topic)
+	return c.sendChatWithFallbacks(ctx, messages)
+}
+
+// buildChatMessages assembles the system prompt, recent history and formatted input for a turn
+func (c *OpenAIClient) buildChatMessages(
+	userInput string,
+	knowledgeLevel models.KnowledgeLevel,
+	mode models.CommunicationMode,
+	conversationHistory []models.ConversationEntry,
+	topic string,
+) []models.Message {
 	// Build the system prompt
-	systemPrompt := GetSystemPrompt(knowledgeLevel, topic)
+	systemPrompt := GetSystemPrompt(knowledgeLevel, topic, c.Prompt)
 	systemPrompt += GetModeInstructions(mode)
 
 	// Build conversation context
 	messages := GetConversationContext(conversationHistory, 10)
+	if c.Prompt.GuardInput {
+		for i := range messages {
+			if messages[i].Role == "user" {
+				messages[i].Content = guardUserInput(messages[i].Content)
+			}
+		}
+	}
 	// Prepend system prompt to ensure the assistant pretends to be a person at the specified knowledge level and responds in voice when in Voice → Voice mode.
 	messages = append([]models.Message{{Role: "system", Content: systemPrompt}}, messages...)
 	
 	// Add the current user input
-	formattedInput := FormatUserInput(userInput, mode)
+	formattedInput := FormatUserInput(userInput, mode, c.Prompt)
 	messages = append(messages, models.Message{
 		Role:    "user",
 		Content: formattedInput,
 	})
-	var requestBody []byte
+	return messages
+}
+
+// sendChatWithFallbacks sends the messages to Model, moving down the fallback list
+// while models are unavailable
+func (c *OpenAIClient) sendChatWithFallbacks(ctx context.Context, messages []models.Message) (ChatResult, error) {
 	var err error
-	if strings.Contains(strings.ToLower(c.Model), "claude") {
-		req := struct {
-			Model    string           `json:"model"`
-			Messages []models.Message `json:"messages"`
-		}{
-			Model:    c.Model,
-			Messages: messages,
+	for _, model := range append([]string{c.Model}, c.Fallbacks...) {
+		var result ChatResult
+		result, err = c.sendChatTo(ctx, model, messages)
+		if err == nil {
+			return result, nil
 		}
-		requestBody, err = json.Marshal(req)
-	} else {
-		req := struct {
-			Model    string           `json:"model"`
-			Messages []models.Message `json:"messages"`
-		}{
-			Model:    c.Model,
-			Messages: messages,
+		if !isModelUnavailable(err) {
+			break
 		}
-		requestBody, err = json.Marshal(req)
 	}
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+	return ChatResult{}, err
+}
+
+// isModelUnavailable reports whether err means the model can't serve requests right now
+// (unknown or retired, rate limited or overloaded), so another model may succeed
+func isModelUnavailable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529:
+		return true
 	}
+	return false
+}
+
+// Complete sends a one-off request with the given system instruction and user text, without any persona or history
+func (c *OpenAIClient) Complete(instruction, text string) (string, error) {
+	return c.sendChat([]models.Message{
+		{Role: "system", Content: instruction},
+		{Role: "user", Content: text},
+	})
+}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", c.BaseURL, bytes.NewBuffer(requestBody))
+// sendChat posts the messages to the chat endpoint and returns the reply text
+func (c *OpenAIClient) sendChat(messages []models.Message) (string, error) {
+	result, err := c.sendChatTo(context.Background(), c.Model, messages)
+	return result.Text, err
+}
+
+// sendChatTo posts the messages to the chat endpoint for model and returns the reply with its token usage
+func (c *OpenAIClient) sendChatTo(ctx context.Context, model string, messages []models.Message) (ChatResult, error) {
+	if c.apiKeyFor(model) == "" {
+		return ChatResult{}, ErrNoAPIKey
+	}
+
+	requestBody, err := c.chatRequestBody(model, messages, false)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return ChatResult{}, err
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	sampled := c.Debug.Sample()
+	start := time.Now()
 
 	// Send the request
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.doChat(ctx, c.HTTPClient, model, requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		if sampled {
+			c.Debug.LogTurn(TurnLog{Phase: "chat", Model: model, Latency: time.Since(start), Request: requestBody, Err: err}, c.apiKeyFor(model))
+		}
+		if ctx.Err() != nil {
+			return ChatResult{}, ctx.Err()
+		}
+		return ChatResult{}, err
 	}
 	defer resp.Body.Close()
 
 	// Read the response
 	body, err := io.ReadAll(resp.Body)
+	if sampled {
+		c.Debug.LogTurn(TurnLog{
+			Phase:    "chat",
+			Model:    model,
+			Status:   resp.StatusCode,
+			Latency:  time.Since(start),
+			Request:  requestBody,
+			Response: body,
+			Err:      err,
+		}, c.apiKeyFor(model))
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		if ctx.Err() != nil {
+			return ChatResult{}, ctx.Err()
+		}
+		return ChatResult{}, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Check for HTTP errors
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return ChatResult{}, chatStatusError(resp.StatusCode, body)
 	}
 
 	// First, try to parse the response as an OpenAI ChatCompletion response
@@ -115,64 +231,165 @@ func (c *OpenAIClient) GenerateResponse(
 			Message struct {
 				Role    string `json:"role"`
 				Content string `json:"content"`
+				Refusal string `json:"refusal"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 	}
+	result := ChatResult{Model: model}
+	result.InputTokens, result.OutputTokens = parseUsage(body)
 	if err := json.Unmarshal(body, &chatResponse); err == nil && len(chatResponse.Choices) > 0 {
-		return chatResponse.Choices[0].Message.Content, nil
+		choice := chatResponse.Choices[0]
+		if choice.FinishReason == "content_filter" || choice.Message.Refusal != "" {
+			return ChatResult{}, ErrContentRefused
+		}
+		result.Text = chatResponse.Choices[0].Message.Content
+		return result, nil
 	}
 	// Fallback: try to parse as a ClaudeResponse
 	var claudeResponse models.ClaudeResponse
 	if err := json.Unmarshal(body, &claudeResponse); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return ChatResult{}, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if claudeResponse.StopReason == "refusal" {
+		return ChatResult{}, ErrContentRefused
 	}
 	if len(claudeResponse.Content) == 0 {
-		return "", fmt.Errorf("no content in response")
+		return ChatResult{}, fmt.Errorf("no content in response")
 	}
-	return claudeResponse.Content[0].Text, nil
+	result.Text = claudeResponse.Content[0].Text
+	return result, nil
 }
 
-// ValidateAPIKey checks if the API key is valid by making a simple request
-func (c *OpenAIClient) ValidateAPIKey() error {
-	testMessages := []models.Message{
-		{
-			Role:    "user",
-			Content: "Hello",
-		},
-	}
-
+// chatRequestBody encodes a chat request for model in the shape its provider expects,
+// asking for a server-sent event stream when stream is set
+func (c *OpenAIClient) chatRequestBody(model string, messages []models.Message, stream bool) ([]byte, error) {
 	var requestBody []byte
 	var err error
-	if strings.Contains(strings.ToLower(c.Model), "claude") {
+	if isClaudeModel(model) {
+		// Anthropic takes the system prompt as a top-level field rather than a message.
+		// It is placed per request, so switching between providers never drops the persona.
+		system, rest := splitSystemPrompt(messages)
 		req := struct {
-			Model    string           `json:"model"`
-			Messages []models.Message `json:"messages"`
+			Model       string           `json:"model"`
+			MaxTokens   int              `json:"max_tokens"`
+			System      string           `json:"system,omitempty"`
+			Messages    []models.Message `json:"messages"`
+			Temperature float64          `json:"temperature,omitempty"`
+			Stream      bool             `json:"stream,omitempty"`
 		}{
-			Model:    c.Model,
-			Messages: testMessages,
+			Model:       model,
+			MaxTokens:   claudeMaxTokens,
+			System:      system,
+			Messages:    rest,
+			Temperature: c.Temperature,
+			Stream:      stream,
 		}
 		requestBody, err = json.Marshal(req)
 	} else {
+		type streamOptions struct {
+			IncludeUsage bool `json:"include_usage"`
+		}
 		req := struct {
-			Model    string           `json:"model"`
-			Messages []models.Message `json:"messages"`
+			Model         string           `json:"model"`
+			Messages      []models.Message `json:"messages"`
+			Temperature   float64          `json:"temperature,omitempty"`
+			Stream        bool             `json:"stream,omitempty"`
+			StreamOptions *streamOptions   `json:"stream_options,omitempty"`
 		}{
-			Model:    c.Model,
-			Messages: testMessages,
+			Model:       model,
+			Messages:    messages,
+			Temperature: c.Temperature,
+			Stream:      stream,
+		}
+		if stream {
+			// Token usage only arrives in a final chunk when asked for
+			req.StreamOptions = &streamOptions{IncludeUsage: true}
 		}
 		requestBody, err = json.Marshal(req)
 	}
 	if err != nil {
-		return fmt.Errorf("failed to marshal test request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
+	return requestBody, nil
+}
 
-	req, err := http.NewRequest("POST", c.BaseURL, bytes.NewBuffer(requestBody))
+// newChatRequest creates an authenticated POST of body to the chat endpoint serving model
+func (c *OpenAIClient) newChatRequest(ctx context.Context, model string, body []byte) (*http.Request, error) {
+	if isClaudeModel(model) && c.Claude != nil {
+		return c.Claude.newRequest(ctx, body)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create test request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	c.Account.setHeaders(req.Header)
+	return req, nil
+}
+
+// chatStatusError turns an error response from the chat endpoint into an error
+func chatStatusError(status int, body []byte) error {
+	if isContextLengthError(status, body) {
+		return fmt.Errorf("%w: %s", ErrContextLengthExceeded, string(body))
+	}
+	return &APIError{StatusCode: status, Body: string(body)}
+}
+
+// claudeMaxTokens caps Anthropic replies, which require an explicit limit
+const claudeMaxTokens = 1024
+
+// splitSystemPrompt separates the system messages, joined in order, from the rest of the conversation
+func splitSystemPrompt(messages []models.Message) (string, []models.Message) {
+	var system []string
+	rest := make([]models.Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Role == "system" {
+			system = append(system, message.Content)
+		} else {
+			rest = append(rest, message)
+		}
+	}
+	return strings.Join(system, "\n\n"), rest
+}
+
+// isContextLengthError reports whether an error response means the prompt was too long.
+// OpenAI uses the context_length_exceeded code, Anthropic a "prompt is too long" message.
+func isContextLengthError(status int, body []byte) bool {
+	if status != http.StatusBadRequest {
+		return false
+	}
+	text := strings.ToLower(string(body))
+	return strings.Contains(text, "context_length_exceeded") ||
+		strings.Contains(text, "maximum context length") ||
+		strings.Contains(text, "prompt is too long")
+}
+
+// ValidateAPIKey checks if the API key is valid by making a simple request
+func (c *OpenAIClient) ValidateAPIKey() error {
+	if c.apiKeyFor(c.Model) == "" {
+		return ErrNoAPIKey
+	}
+
+	testMessages := []models.Message{
+		{
+			Role:    "user",
+			Content: "Hello",
+		},
+	}
+
+	requestBody, err := c.chatRequestBody(c.Model, testMessages, false)
+	if err != nil {
+		return fmt.Errorf("failed to marshal test request: %w", err)
+	}
+
+	req, err := c.newChatRequest(context.Background(), c.Model, requestBody)
+	if err != nil {
+		return fmt.Errorf("failed to create test request: %w", err)
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -191,58 +408,3 @@ func (c *OpenAIClient) ValidateAPIKey() error {
 
 	return nil
 }
-
-func (c *OpenAIClient) FetchAvailableModels() ([]string, error) {
-	url := "https://api.openai.com/v1/models"
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to fetch models, status %d: %s", resp.StatusCode, string(body))
-	}
-	var result struct {
-		Data []struct {
-			ID string `json:"id"`
-		} `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-	models := make([]string, 0, len(result.Data))
-	for _, m := range result.Data {
-		id := m.ID
-		// Skip models with a date timestamp pattern (e.g. "2023-03-14")
-		if matched, _ := regexp.MatchString(`\d{4}-\d{2}-\d{2}`, id); matched {
-			continue
-		}
-		// Skip models not suitable for text-to-text communication (e.g. Whisper models, DALL·E variants, embeddings, TTS, moderation, realtime, research, image, transcribe, audio, instruct, davinci, babbage, codex, search)
-		if strings.Contains(id, "whisper") ||
-			strings.Contains(id, "dalle") ||
-			strings.Contains(id, "dall-e") ||
-			strings.Contains(id, "embedding") ||
-			strings.Contains(id, "instruct") ||
-			strings.Contains(id, "davinci") ||
-			strings.Contains(id, "babbage") ||
-			strings.Contains(id, "codex") ||
-			strings.Contains(id, "tts") ||
-			strings.Contains(id, "moderation") ||
-			strings.Contains(id, "realtime") ||
-			strings.Contains(id, "research") ||
-			strings.Contains(id, "image") ||
-			strings.Contains(id, "transcribe") ||
-			strings.Contains(id, "audio") ||
-			strings.Contains(id, "search") {
-			continue
-		}
-		models = append(models, id)
-	}
-	return models, nil
-}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// chatRequest is the part of a serialized chat request both providers share, with
+// Anthropic's top-level system prompt
+type chatRequest struct {
+	Model    string           `json:"model"`
+	System   *string          `json:"system"`
+	Messages []models.Message `json:"messages"`
+}
+
+func TestChatRequestBodyPersonaAcrossProviderSwitch(t *testing.T) {
+	const level, mode = models.FreshmanUniversity, models.TextToText
+	c := NewOpenAIClient("test-key", "")
+	persona := GetSystemPrompt(level, "photosynthesis", c.Prompt) + GetModeInstructions(mode)
+
+	var history []models.ConversationEntry
+	for turn, model := range []string{"gpt-4o", "claude-3-5-sonnet-latest", "gpt-4o-mini", "claude-3-5-haiku-latest"} {
+		input := "explanation " + string(rune('A'+turn))
+		messages := c.buildChatMessages(input, level, mode, history, "photosynthesis")
+		body, err := c.chatRequestBody(model, messages, false)
+		if err != nil {
+			t.Fatalf("turn %d (%s): %v", turn+1, model, err)
+		}
+		var req chatRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("turn %d (%s): decoding request: %v", turn+1, model, err)
+		}
+
+		if isClaudeModel(model) {
+			if req.System == nil || *req.System != persona {
+				t.Errorf("turn %d (%s): system = %v, want the persona", turn+1, model, req.System)
+			}
+			for _, message := range req.Messages {
+				if message.Role == "system" {
+					t.Errorf("turn %d (%s): system message in messages, Anthropic only takes the top-level field", turn+1, model)
+				}
+			}
+			if len(req.Messages) == 0 || req.Messages[0].Role != "user" {
+				t.Errorf("turn %d (%s): messages don't start with a user message", turn+1, model)
+			}
+		} else {
+			if req.System != nil {
+				t.Errorf("turn %d (%s): top-level system field sent to OpenAI", turn+1, model)
+			}
+			if len(req.Messages) == 0 || req.Messages[0].Role != "system" || req.Messages[0].Content != persona {
+				t.Errorf("turn %d (%s): messages[0] isn't the persona system message", turn+1, model)
+			}
+		}
+		if last := req.Messages[len(req.Messages)-1]; last.Role != "user" || last.Content != input {
+			t.Errorf("turn %d (%s): last message = %+v, want the user input", turn+1, model, last)
+		}
+		if want := 2*turn + 1; len(req.Messages) < want {
+			t.Errorf("turn %d (%s): %d conversation messages, want at least %d", turn+1, model, len(req.Messages), want)
+		}
+
+		history = append(history, models.ConversationEntry{UserInput: input, AIResponse: "reply " + input, Mode: mode, KnowledgeLevel: level})
+	}
+}
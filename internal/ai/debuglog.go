@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyPatterns match credentials that must never reach the debug log
+var keyPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{8,}`),
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9_\-\.]+`),
+}
+
+// Debug log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// TurnLog describes a single request/response exchange for the debug log
+type TurnLog struct {
+	Phase    string // pipeline stage, e.g. "chat"
+	Model    string
+	Status   int
+	Latency  time.Duration
+	Request  []byte
+	Response []byte
+	Err      error
+}
+
+// DebugLogger writes full request/response payloads for a sampled fraction of turns
+type DebugLogger struct {
+	mutex      sync.Mutex
+	out        io.Writer
+	sampleRate float64
+	format     string
+}
+
+// NewDebugLogger creates a debug logger that records roughly sampleRate (0.0–1.0) of turns.
+// format is LogFormatText for human-readable entries or LogFormatJSON for one JSON object per line.
+func NewDebugLogger(out io.Writer, sampleRate float64, format string) *DebugLogger {
+	if sampleRate < 0 {
+		sampleRate = 0
+	}
+	if sampleRate > 1 {
+		sampleRate = 1
+	}
+	if format != LogFormatJSON {
+		format = LogFormatText
+	}
+	return &DebugLogger{
+		out:        out,
+		sampleRate: sampleRate,
+		format:     format,
+	}
+}
+
+// Sample decides whether the next turn should be logged. A nil logger never samples.
+func (d *DebugLogger) Sample() bool {
+	if d == nil || d.sampleRate == 0 {
+		return false
+	}
+	return d.sampleRate >= 1 || rand.Float64() < d.sampleRate
+}
+
+// LogTurn writes a turn to the log with secrets redacted
+func (d *DebugLogger) LogTurn(turn TurnLog, secrets ...string) {
+	if d == nil {
+		return
+	}
+
+	errText := ""
+	if turn.Err != nil {
+		errText = turn.Err.Error()
+	}
+	inputTokens, outputTokens := parseUsage(turn.Response)
+
+	var line string
+	if d.format == LogFormatJSON {
+		data, err := json.Marshal(struct {
+			Timestamp    string `json:"timestamp"`
+			Phase        string `json:"phase"`
+			Model        string `json:"model"`
+			Status       int    `json:"status"`
+			LatencyMS    int64  `json:"latency_ms"`
+			InputTokens  int    `json:"input_tokens"`
+			OutputTokens int    `json:"output_tokens"`
+			Error        string `json:"error,omitempty"`
+			Request      string `json:"request"`
+			Response     string `json:"response"`
+		}{
+			Timestamp:    time.Now().Format(time.RFC3339),
+			Phase:        turn.Phase,
+			Model:        turn.Model,
+			Status:       turn.Status,
+			LatencyMS:    turn.Latency.Milliseconds(),
+			InputTokens:  inputTokens,
+			OutputTokens: outputTokens,
+			Error:        errText,
+			Request:      string(turn.Request),
+			Response:     string(turn.Response),
+		})
+		if err != nil {
+			return
+		}
+		line = string(data) + "\n"
+	} else {
+		var b strings.Builder
+		fmt.Fprintf(&b, "=== %s phase=%s model=%s status=%d latency=%s tokens=%d/%d\n",
+			time.Now().Format(time.RFC3339), turn.Phase, turn.Model, turn.Status, turn.Latency.Round(time.Millisecond), inputTokens, outputTokens)
+		if errText != "" {
+			fmt.Fprintf(&b, "error: %s\n", errText)
+		}
+		fmt.Fprintf(&b, "request: %s\n", turn.Request)
+		fmt.Fprintf(&b, "response: %s\n\n", turn.Response)
+		line = b.String()
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	io.WriteString(d.out, redact(line, secrets...))
+}
+
+// parseUsage extracts token counts from an OpenAI or Anthropic response body
+func parseUsage(body []byte) (int, int) {
+	var usage struct {
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			InputTokens      int `json:"input_tokens"`
+			OutputTokens     int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &usage); err != nil {
+		return 0, 0
+	}
+	return usage.Usage.PromptTokens + usage.Usage.InputTokens, usage.Usage.CompletionTokens + usage.Usage.OutputTokens
+}
+
+// redact replaces known secrets and anything shaped like an API key
+func redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+	for _, pattern := range keyPatterns {
+		s = pattern.ReplaceAllString(s, "[REDACTED]")
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrContentRefused is returned when the model declines to answer or moderation flags the input
+var ErrContentRefused = errors.New("the model declined to answer this")
+
+// moderationURL returns the moderation endpoint alongside the chat endpoint
+func (c *OpenAIClient) moderationURL() string {
+	return strings.TrimSuffix(c.BaseURL, "/chat/completions") + "/moderations"
+}
+
+// Moderate checks text against the moderation endpoint and reports whether it was flagged
+func (c *OpenAIClient) Moderate(text string) (bool, error) {
+	if c.APIKey == "" {
+		return false, ErrNoAPIKey
+	}
+
+	requestBody, err := json.Marshal(struct {
+		Input string `json:"input"`
+	}{Input: text})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.moderationURL(), bytes.NewBuffer(requestBody))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	c.Account.setHeaders(req.Header)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var moderation struct {
+		Results []struct {
+			Flagged bool `json:"flagged"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &moderation); err != nil {
+		return false, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	for _, result := range moderation.Results {
+		if result.Flagged {
+			return true, nil
+		}
+	}
+	return false, nil
+}
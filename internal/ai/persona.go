@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// personaBreakPhrases are telltale signs of a response that dropped its learner persona
+var personaBreakPhrases = []string{
+	"as an ai",
+	"as a language model",
+	"i am an ai",
+	"i'm an ai",
+	"i am a language model",
+	"i'm a language model",
+	"as an assistant",
+	"i'm just an ai",
+	"role-playing as",
+	"role-play as",
+}
+
+// BreaksPersona reports whether the response contains an obvious out-of-character phrase
+func BreaksPersona(response string) bool {
+	lower := strings.ToLower(response)
+	for _, phrase := range personaBreakPhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// StaysInPersona judges whether the response stayed in character for the level.
+// Obvious breaks are caught without a request; otherwise a short yes/no completion decides.
+func (c *OpenAIClient) StaysInPersona(level models.KnowledgeLevel, response string) (bool, error) {
+	if BreaksPersona(response) {
+		return false, nil
+	}
+
+	instruction := fmt.Sprintf(`You review replies written by someone role-playing a learner: %s. `+
+		`The learner should react to an explanation, asking questions at that level, and never lecture, `+
+		`mention being an AI, or answer beyond that level. Reply with the single word YES if the reply stays in character, or NO if it does not.`,
+		level.String())
+	verdict, err := c.Complete(instruction, response)
+	if err != nil {
+		return true, fmt.Errorf("failed to judge persona: %w", err)
+	}
+	return !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(verdict)), "NO"), nil
+}
@@ -0,0 +1,171 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Provider identifies the backend a chat client is talking to
+type Provider string
+
+const (
+	ProviderOpenAI    Provider = "openai"
+	ProviderAnthropic Provider = "anthropic"
+	ProviderLocal     Provider = "local"
+)
+
+// UnsupportedListingError is returned when a provider offers no way to enumerate its models
+type UnsupportedListingError struct {
+	Provider Provider
+}
+
+func (e *UnsupportedListingError) Error() string {
+	return fmt.Sprintf("model listing is not supported for provider %s", e.Provider)
+}
+
+// openAIChatFamilies lists the OpenAI model families usable for chat completions
+var openAIChatFamilies = []string{
+	"gpt-3.5-turbo",
+	"gpt-4",
+	"gpt-4o",
+	"gpt-4.1",
+	"gpt-4.5",
+	"gpt-5",
+	"chatgpt-4o",
+	"o1",
+	"o3",
+	"o4",
+}
+
+// openAIChatVariants lists the name suffixes that keep a model chat-capable
+var openAIChatVariants = map[string]bool{
+	"mini":    true,
+	"nano":    true,
+	"turbo":   true,
+	"preview": true,
+	"latest":  true,
+	"pro":     true,
+	"16k":     true,
+	"32k":     true,
+}
+
+// anthropicModels lists the known Claude chat models
+var anthropicModels = []string{
+	"claude-opus-4-20250514",
+	"claude-sonnet-4-20250514",
+	"claude-3-7-sonnet-20250219",
+	"claude-3-5-sonnet-20241022",
+	"claude-3-5-haiku-20241022",
+	"claude-3-opus-20240229",
+}
+
+var snapshotPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// Provider reports which backend the client is configured for
+func (c *OpenAIClient) Provider() Provider {
+	if isClaudeModel(c.Model) {
+		return ProviderAnthropic
+	}
+	if u, err := url.Parse(c.BaseURL); err == nil && u.Host == "api.openai.com" {
+		return ProviderOpenAI
+	}
+	return ProviderLocal
+}
+
+// FetchAvailableModels returns the chat-capable models offered by the client's provider
+func (c *OpenAIClient) FetchAvailableModels() ([]string, error) {
+	switch c.Provider() {
+	case ProviderAnthropic:
+		return append([]string(nil), anthropicModels...), nil
+	case ProviderOpenAI:
+		ids, err := c.listModelIDs()
+		if err != nil {
+			return nil, err
+		}
+		models := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if isOpenAIChatModel(id) {
+				models = append(models, id)
+			}
+		}
+		return models, nil
+	default:
+		// Local servers only expose what they are able to serve, so no filtering is applied
+		return c.listModelIDs()
+	}
+}
+
+// modelsURL derives the models endpoint from the chat completions URL
+func (c *OpenAIClient) modelsURL() string {
+	return strings.TrimSuffix(c.BaseURL, "/chat/completions") + "/models"
+}
+
+// listModelIDs queries the OpenAI-compatible models endpoint
+func (c *OpenAIClient) listModelIDs() ([]string, error) {
+	if c.APIKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	req, err := http.NewRequest("GET", c.modelsURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	c.Account.setHeaders(req.Header)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, &UnsupportedListingError{Provider: c.Provider()}
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to fetch models, status %d: %s", resp.StatusCode, string(body))
+	}
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(result.Data))
+	for _, m := range result.Data {
+		ids = append(ids, m.ID)
+	}
+	return ids, nil
+}
+
+// isOpenAIChatModel reports whether id belongs to a chat-capable family.
+// Dated snapshots and variants such as audio, realtime or tts are rejected.
+func isOpenAIChatModel(id string) bool {
+	if snapshotPattern.MatchString(id) {
+		return false
+	}
+	for _, family := range openAIChatFamilies {
+		if id == family {
+			return true
+		}
+		if !strings.HasPrefix(id, family+"-") {
+			continue
+		}
+		ok := true
+		for _, part := range strings.Split(strings.TrimPrefix(id, family+"-"), "-") {
+			if !openAIChatVariants[part] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
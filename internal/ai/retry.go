@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	retryBaseDelay = time.Second      // wait before the first retry, doubled for each one after
+	maxRetryDelay  = 30 * time.Second // cap on the backoff between retries
+	maxRetryAfter  = time.Minute      // cap on a server-requested Retry-After wait
+)
+
+// doChat posts body to the chat endpoint serving model with client, retrying up to MaxRetries times while
+// the response is a rate limit or transient server error. Once retries run out the last
+// response is returned as is, so the caller reports its error.
+func (c *OpenAIClient) doChat(ctx context.Context, client *http.Client, model string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := c.newChatRequest(ctx, model, body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		if attempt >= c.MaxRetries || !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		delay := retryDelay(attempt, resp.Header.Get("Retry-After"))
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// isRetryableStatus reports whether a chat request failing with status may succeed if sent again.
+// Client errors such as 400 and 401 will fail the same way every time.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before retrying after the given zero-based attempt.
+// A Retry-After header, in seconds or as an HTTP date, is honored; otherwise the delay
+// doubles with each attempt, with jitter so clients that failed together don't retry together.
+func retryDelay(attempt int, retryAfter string) time.Duration {
+	if retryAfter != "" {
+		var delay time.Duration
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			delay = time.Duration(seconds) * time.Second
+		} else if at, err := http.ParseTime(retryAfter); err == nil {
+			delay = time.Until(at)
+		}
+		if delay > 0 {
+			return min(delay, maxRetryAfter)
+		}
+	}
+	backoff := maxRetryDelay
+	if attempt < 5 {
+		backoff = min(retryBaseDelay<<attempt, maxRetryDelay)
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SpeechFilters selects the clean-ups applied to text before it is synthesized.
+// They only affect what is spoken; displayed and logged text is left intact.
+type SpeechFilters struct {
+	StripMarkdown       bool
+	SpellOutURLs        bool
+	ExpandAbbreviations bool
+	RemoveEmoji         bool
+}
+
+var (
+	codeFencePattern  = regexp.MustCompile("(?m)^\\s*```.*$")
+	inlineCodePattern = regexp.MustCompile("`([^`]*)`")
+	headingPattern    = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	bulletPattern     = regexp.MustCompile(`(?m)^\s*[-*+]\s+`)
+	quotePattern      = regexp.MustCompile(`(?m)^\s*>\s?`)
+	mdLinkPattern     = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	starEmphasis      = regexp.MustCompile(`\*{1,2}([^*\n]+)\*{1,2}`)
+	underEmphasis     = regexp.MustCompile(`(^|\W)_{1,2}([^_\n]+)_{1,2}(\W|$)`)
+	tableRulePattern  = regexp.MustCompile(`(?m)^\s*\|?[\s:|-]+\|[\s:|-]*$`)
+	urlPattern        = regexp.MustCompile(`https?://(?:www\.)?([^\s/?#]+)[^\s]*`)
+	spacePattern      = regexp.MustCompile(`[ \t]+`)
+)
+
+// abbreviations maps written abbreviations to their spoken form
+var abbreviations = []struct {
+	pattern *regexp.Regexp
+	spoken  string
+}{
+	{regexp.MustCompile(`\be\.g\.`), "for example"},
+	{regexp.MustCompile(`\bi\.e\.`), "that is"},
+	{regexp.MustCompile(`\betc\.`), "et cetera"},
+	{regexp.MustCompile(`\bvs\.?\s`), "versus "},
+	{regexp.MustCompile(`\bapprox\.`), "approximately"},
+	{regexp.MustCompile(`\bw/o\b`), "without"},
+	{regexp.MustCompile(`\bw/\s`), "with "},
+}
+
+// Apply runs the enabled filters over text
+func (f SpeechFilters) Apply(text string) string {
+	if f.StripMarkdown {
+		text = stripMarkdown(text)
+	}
+	if f.SpellOutURLs {
+		text = spellOutURLs(text)
+	}
+	if f.ExpandAbbreviations {
+		text = expandAbbreviations(text)
+	}
+	if f.RemoveEmoji {
+		text = removeEmoji(text)
+	}
+	return strings.TrimSpace(spacePattern.ReplaceAllString(text, " "))
+}
+
+// stripMarkdown removes formatting syntax, keeping the text it decorates
+func stripMarkdown(text string) string {
+	text = codeFencePattern.ReplaceAllString(text, "")
+	text = tableRulePattern.ReplaceAllString(text, "")
+	text = mdLinkPattern.ReplaceAllString(text, "$1")
+	text = inlineCodePattern.ReplaceAllString(text, "$1")
+	text = headingPattern.ReplaceAllString(text, "")
+	text = bulletPattern.ReplaceAllString(text, "")
+	text = quotePattern.ReplaceAllString(text, "")
+	text = starEmphasis.ReplaceAllString(text, "$1")
+	text = underEmphasis.ReplaceAllString(text, "$1$2$3")
+	return strings.ReplaceAll(text, "|", " ")
+}
+
+// spellOutURLs replaces URLs with a speakable mention of their domain
+func spellOutURLs(text string) string {
+	return urlPattern.ReplaceAllStringFunc(text, func(url string) string {
+		domain := urlPattern.FindStringSubmatch(url)[1]
+		return "a link to " + strings.ReplaceAll(domain, ".", " dot ")
+	})
+}
+
+// expandAbbreviations replaces common abbreviations with full words
+func expandAbbreviations(text string) string {
+	for _, abbr := range abbreviations {
+		text = abbr.pattern.ReplaceAllString(text, abbr.spoken)
+	}
+	return text
+}
+
+// removeEmoji drops emoji, pictographs and the joiners that combine them
+func removeEmoji(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0x1F000 && r <= 0x1FAFF, // pictographs, emoticons, flags
+			r >= 0x2600 && r <= 0x27BF, // miscellaneous symbols and dingbats
+			r >= 0x2B00 && r <= 0x2BFF, // arrows and stars often used as emoji
+			r == 0xFE0F, r == 0x200D:   // variation selector and zero-width joiner
+			return -1
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,98 @@
+package ai
+
+import "testing"
+
+// filterCase is an input to a speech filter and the text it should produce
+type filterCase struct {
+	name string
+	in   string
+	want string
+}
+
+// runFilterCases checks filter against each case
+func runFilterCases(t *testing.T, filter func(string) string, tests []filterCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripMarkdown(t *testing.T) {
+	runFilterCases(t, stripMarkdown, []filterCase{
+		{"plain", "Nothing to strip.", "Nothing to strip."},
+		{"heading", "## Photosynthesis\nPlants make food.", "Photosynthesis\nPlants make food."},
+		{"bullets", "- light\n* water\n+ air", "light\nwater\nair"},
+		{"quote", "> so you mean", "so you mean"},
+		{"bold and italic", "It is **very** *really* important", "It is very really important"},
+		{"underscore emphasis", "a __big__ and _small_ thing", "a big and small thing"},
+		{"snake case kept", "call load_config now", "call load_config now"},
+		{"inline code", "run `go test` first", "run go test first"},
+		{"code fence", "```go\nfmt.Println()\n```", "\nfmt.Println()\n"},
+		{"link", "see [the docs](https://example.com/docs)", "see the docs"},
+		{"table", "| a | b |\n|---|---|\n| 1 | 2 |", "  a   b  \n\n  1   2  "},
+	})
+}
+
+func TestSpellOutURLs(t *testing.T) {
+	runFilterCases(t, spellOutURLs, []filterCase{
+		{"no url", "no links here", "no links here"},
+		{"https", "see https://go.dev/doc/effective_go for more", "see a link to go dot dev for more"},
+		{"www dropped", "at http://www.example.com", "at a link to example dot com"},
+		{"query and fragment", "https://example.org?q=1#top", "a link to example dot org"},
+		{"two urls", "https://a.io and https://b.io/x", "a link to a dot io and a link to b dot io"},
+	})
+}
+
+func TestExpandAbbreviations(t *testing.T) {
+	runFilterCases(t, expandAbbreviations, []filterCase{
+		{"none", "nothing to expand", "nothing to expand"},
+		{"e.g.", "fruit, e.g. apples", "fruit, for example apples"},
+		{"i.e.", "the sun, i.e. a star", "the sun, that is a star"},
+		{"etc.", "red, green, etc.", "red, green, et cetera"},
+		{"vs", "cats vs dogs and cats vs. dogs", "cats versus dogs and cats versus dogs"},
+		{"approx.", "approx. ten", "approximately ten"},
+		{"with and without", "tea w/ milk, w/o sugar", "tea with milk, without sugar"},
+		{"inside words", "vest, teg.", "vest, teg."},
+	})
+}
+
+func TestRemoveEmoji(t *testing.T) {
+	runFilterCases(t, removeEmoji, []filterCase{
+		{"none", "plain text", "plain text"},
+		{"pictographs", "great 👍 job 🎉", "great  job "},
+		{"symbols", "sunny ☀️ and ✨", "sunny  and "},
+		{"joined sequence", "family 👨‍👩‍👧 time", "family  time"},
+		{"accents kept", "café naïve", "café naïve"},
+	})
+}
+
+func TestSpeechFiltersApply(t *testing.T) {
+	const text = "**Note**: see https://go.dev, e.g. the tour 🚀"
+	tests := []struct {
+		name    string
+		filters SpeechFilters
+		want    string
+	}{
+		{"none", SpeechFilters{}, text},
+		{"markdown only", SpeechFilters{StripMarkdown: true}, "Note: see https://go.dev, e.g. the tour 🚀"},
+		{"urls only", SpeechFilters{SpellOutURLs: true}, "**Note**: see a link to go dot dev, e.g. the tour 🚀"},
+		{"abbreviations only", SpeechFilters{ExpandAbbreviations: true}, "**Note**: see https://go.dev, for example the tour 🚀"},
+		{"emoji only", SpeechFilters{RemoveEmoji: true}, "**Note**: see https://go.dev, e.g. the tour"},
+		{
+			"all",
+			SpeechFilters{StripMarkdown: true, SpellOutURLs: true, ExpandAbbreviations: true, RemoveEmoji: true},
+			"Note: see a link to go dot dev, for example the tour",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filters.Apply(text); got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// errStreamEnded is returned when a response stream closes before its end marker
+var errStreamEnded = errors.New("response stream ended early")
+
+// maxStreamLine bounds a single server-sent event line
+const maxStreamLine = 1 << 20
+
+// GenerateResponseStream is GenerateResponse that delivers the reply as text deltas while it
+// is generated. Both channels are closed when the stream ends; a failure, including
+// cancellation of ctx, is sent on the error channel before it closes.
+func (c *OpenAIClient) GenerateResponseStream(
+	ctx context.Context,
+	userInput string,
+	knowledgeLevel models.KnowledgeLevel,
+	mode models.CommunicationMode,
+	conversationHistory []models.ConversationEntry,
+	topic string,
+) (<-chan string, <-chan error) {
+	chunks := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(chunks)
+		_, err := c.StreamResponseFrom(ctx, userInput, knowledgeLevel, mode, conversationHistory, topic, func(delta string) {
+			select {
+			case chunks <- delta:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return chunks, errs
+}
+
+// StreamResponseFrom is GenerateResponseFrom that calls onDelta with each piece of the reply
+// as it arrives. The returned result holds the full text once the stream is complete.
+// Fallback models aren't tried, as part of the reply may already have been shown.
+func (c *OpenAIClient) StreamResponseFrom(
+	ctx context.Context,
+	userInput string,
+	knowledgeLevel models.KnowledgeLevel,
+	mode models.CommunicationMode,
+	conversationHistory []models.ConversationEntry,
+	topic string,
+	onDelta func(string),
+) (ChatResult, error) {
+	messages := c.buildChatMessages(userInput, knowledgeLevel, mode, conversationHistory, topic)
+	return c.streamChatTo(ctx, c.Model, messages, onDelta)
+}
+
+// streamChatTo posts the messages to the chat endpoint for model as a streaming request
+func (c *OpenAIClient) streamChatTo(ctx context.Context, model string, messages []models.Message, onDelta func(string)) (ChatResult, error) {
+	if c.apiKeyFor(model) == "" {
+		return ChatResult{}, ErrNoAPIKey
+	}
+	requestBody, err := c.chatRequestBody(model, messages, true)
+	if err != nil {
+		return ChatResult{}, err
+	}
+
+	// The client timeout would cut off long replies, so the stream is bounded by ctx instead
+	client := *c.HTTPClient
+	client.Timeout = 0
+
+	sampled := c.Debug.Sample()
+	start := time.Now()
+	result, status, err := func() (ChatResult, int, error) {
+		resp, err := c.doChat(ctx, &client, model, requestBody)
+		if err != nil {
+			return ChatResult{}, 0, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return ChatResult{}, resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+			}
+			return ChatResult{}, resp.StatusCode, chatStatusError(resp.StatusCode, body)
+		}
+		result, err := readChatStream(resp.Body, onDelta)
+		result.Model = model
+		return result, resp.StatusCode, err
+	}()
+	if ctx.Err() != nil {
+		// Reads fail in various ways once the request is cancelled; report the cancellation
+		err = ctx.Err()
+	}
+	if sampled {
+		c.Debug.LogTurn(TurnLog{
+			Phase:    "chat",
+			Model:    model,
+			Status:   status,
+			Latency:  time.Since(start),
+			Request:  requestBody,
+			Response: []byte(result.Text),
+			Err:      err,
+		}, c.apiKeyFor(model))
+	}
+	if err != nil {
+		return ChatResult{}, err
+	}
+	return result, nil
+}
+
+// streamEvent is the union of the OpenAI and Anthropic streaming event payloads
+type streamEvent struct {
+	Type    string `json:"type"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+			Refusal string `json:"refusal"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Delta struct {
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Message struct {
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		OutputTokens     int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readChatStream reads server-sent events from r until the end marker, calling onDelta
+// for each piece of text. OpenAI ends with "[DONE]", Anthropic with a message_stop event.
+func readChatStream(r io.Reader, onDelta func(string)) (ChatResult, error) {
+	var result ChatResult
+	var text strings.Builder
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			// Blank separators, event names and keep-alive comments
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			result.Text = text.String()
+			return result, nil
+		}
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return ChatResult{}, fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+		if event.Error != nil {
+			return ChatResult{}, fmt.Errorf("stream failed: %s", event.Error.Message)
+		}
+		if event.Usage != nil {
+			result.InputTokens += event.Usage.PromptTokens
+			result.OutputTokens += event.Usage.CompletionTokens + event.Usage.OutputTokens
+		}
+
+		var delta string
+		switch event.Type {
+		case "message_start":
+			result.InputTokens += event.Message.Usage.InputTokens
+		case "content_block_delta":
+			delta = event.Delta.Text
+		case "message_delta":
+			if event.Delta.StopReason == "refusal" {
+				return ChatResult{}, ErrContentRefused
+			}
+		case "message_stop":
+			result.Text = text.String()
+			return result, nil
+		}
+		for _, choice := range event.Choices {
+			if choice.FinishReason == "content_filter" || choice.Delta.Refusal != "" {
+				return ChatResult{}, ErrContentRefused
+			}
+			delta += choice.Delta.Content
+		}
+		if delta != "" {
+			text.WriteString(delta)
+			onDelta(delta)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ChatResult{}, fmt.Errorf("failed to read response stream: %w", err)
+	}
+	return ChatResult{}, errStreamEnded
+}
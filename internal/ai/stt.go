@@ -2,30 +2,124 @@ package ai
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/sashabaranov/go-openai"
 )
 
 // STTClient handles speech-to-text conversion using OpenAI Whisper
 type STTClient struct {
-	client *openai.Client
-	model  string
+	client           *openai.Client
+	apiKey           string
+	model            string
+	baseTimeout      time.Duration
+	timeoutPerSecond float64
+	maxRetries       int
+	language         string // ISO-639-1 hint for the spoken language, empty to auto-detect
+	prompt           string // context such as vocabulary the speech is likely to use
 }
 
+// Whisper only considers the last 224 tokens of a prompt. Without its tokenizer at hand, the
+// prompt is cut to a character count that stays under that for typical text.
+const (
+	maxSTTPromptTokens = 224
+	sttCharsPerToken   = 3
+)
+
 // NewSTTClient creates a new STT client
 func NewSTTClient(apiKey, model string) *STTClient {
 	return &STTClient{
-		client: openai.NewClient(apiKey),
-		model:  model,
+		client:           openai.NewClient(apiKey),
+		apiKey:           apiKey,
+		model:            model,
+		baseTimeout:      60 * time.Second,
+		timeoutPerSecond: 0,
+		maxRetries:       0,
+	}
+}
+
+// SetAccount routes the client's requests to an OpenAI organization and project
+func (s *STTClient) SetAccount(account Account) {
+	s.client = newSDKClient(s.apiKey, account)
+}
+
+// SetTimeout sets the upload timeout to base plus perSecond seconds for every second of audio
+func (s *STTClient) SetTimeout(base time.Duration, perSecond float64) {
+	if base <= 0 {
+		base = 60 * time.Second
+	}
+	s.baseTimeout = base
+	s.timeoutPerSecond = perSecond
+}
+
+// SetMaxRetries sets how many times a transient transcription failure is retried
+func (s *STTClient) SetMaxRetries(retries int) {
+	if retries < 0 {
+		retries = 0
 	}
+	s.maxRetries = retries
+}
+
+// SetLanguage sets the ISO-639-1 code, such as "es", of the language recordings are in.
+// Empty leaves the language to be detected, which can misfire on short utterances.
+func (s *STTClient) SetLanguage(language string) {
+	s.language = language
+}
+
+// SetPrompt sets text, such as the conversation so far or domain terms, that guides the
+// spelling and vocabulary of transcriptions. Only its end is sent when it is too long.
+func (s *STTClient) SetPrompt(prompt string) {
+	s.prompt = prompt
 }
 
 // SpeechToText converts audio file to text
 func (s *STTClient) SpeechToText(audioFilePath string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	return s.SpeechToTextContext(context.Background(), audioFilePath)
+}
+
+// SpeechToTextContext is SpeechToText that gives up, including between retries, once ctx is cancelled
+func (s *STTClient) SpeechToTextContext(ctx context.Context, audioFilePath string) (string, error) {
+	if s.apiKey == "" {
+		return "", ErrNoAPIKey
+	}
+
+	timeout := s.baseTimeout + time.Duration(s.timeoutPerSecond*wavDuration(audioFilePath).Seconds()*float64(time.Second))
+
+	var err error
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(1<<(attempt-1)) * time.Second):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+
+		var text string
+		text, err = s.transcribe(ctx, audioFilePath, timeout)
+		if err == nil {
+			return text, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if !isTransientSTTError(err) {
+			break
+		}
+	}
+	return "", err
+}
+
+// transcribe makes a single transcription request, reopening the file so retries upload it from the start
+func (s *STTClient) transcribe(ctx context.Context, audioFilePath string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Open the audio file
@@ -40,6 +134,8 @@ func (s *STTClient) SpeechToText(audioFilePath string) (string, error) {
 		Model:    s.model,
 		FilePath: audioFilePath,
 		Reader:   audioFile,
+		Language: s.language,
+		Prompt:   truncateSTTPrompt(s.prompt),
 	}
 
 	// Make the request
@@ -51,12 +147,86 @@ func (s *STTClient) SpeechToText(audioFilePath string) (string, error) {
 	return response.Text, nil
 }
 
-// ValidateAPIKey checks if the OpenAI API key is valid for STT
+// truncateSTTPrompt keeps the end of prompt that fits Whisper's prompt limit, starting at a word
+func truncateSTTPrompt(prompt string) string {
+	runes := []rune(strings.TrimSpace(prompt))
+	limit := maxSTTPromptTokens * sttCharsPerToken
+	if len(runes) <= limit {
+		return string(runes)
+	}
+	tail := string(runes[len(runes)-limit:])
+	if i := strings.IndexFunc(tail, unicode.IsSpace); i >= 0 {
+		tail = tail[i:]
+	}
+	return strings.TrimSpace(tail)
+}
+
+// isTransientSTTError reports whether a transcription failure is worth retrying
+func isTransientSTTError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if code := statusCode(err); code != 0 {
+		return code == 429 || code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// wavDuration estimates the length of a WAV file from its header, returning zero if it can't be read
+func wavDuration(path string) time.Duration {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	header := make([]byte, 44)
+	if _, err := file.Read(header); err != nil || string(header[0:4]) != "RIFF" {
+		return 0
+	}
+	byteRate := binary.LittleEndian.Uint32(header[28:32])
+	dataSize := binary.LittleEndian.Uint32(header[40:44])
+	if byteRate == 0 {
+		return 0
+	}
+	return time.Duration(float64(dataSize) / float64(byteRate) * float64(time.Second))
+}
+
+// ValidateAPIKey checks that the API key is accepted and can use the transcription model.
+// It looks the model up rather than uploading audio, with a short timeout so startup stays quick.
 func (s *STTClient) ValidateAPIKey() error {
-	// For STT validation, we'll just check if we can create a client
-	// A full validation would require a test audio file
+	if s.apiKey == "" {
+		return ErrNoAPIKey
+	}
 	if s.client == nil {
 		return fmt.Errorf("invalid OpenAI client")
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.client.GetModel(ctx, s.model); err != nil {
+		switch statusCode(err) {
+		case 401:
+			return fmt.Errorf("invalid API key: %w", err)
+		case 404:
+			return fmt.Errorf("transcription model %q is not available: %w", s.model, err)
+		}
+		return fmt.Errorf("failed to check STT access: %w", err)
+	}
 	return nil
 }
+
+// statusCode returns the HTTP status of a failed go-openai request, or 0 if it never got one
+func statusCode(err error) int {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode
+	}
+	return 0
+}
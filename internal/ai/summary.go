@@ -0,0 +1,28 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// sessionSummaryInstruction asks for a recap of what the user has explained so far
+const sessionSummaryInstruction = "The following is a teaching session in which the user explains topics to a learner. Summarize what has been covered so far as at most five short bullet points starting with \"- \". Reply with the bullet points only."
+
+// SummarizeSession returns a brief bullet summary of the turns covered in entries
+func (c *OpenAIClient) SummarizeSession(entries []models.ConversationEntry) (string, error) {
+	var transcript strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&transcript, "User: %s\nLearner: %s\n\n", entry.UserInput, entry.AIResponse)
+	}
+	summary, err := c.Complete(sessionSummaryInstruction, transcript.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize session: %w", err)
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("failed to summarize session: empty reply")
+	}
+	return summary, nil
+}
@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// maxTitleWords bounds titles derived from the first input
+const maxTitleWords = 5
+
+// maxTitleRunes bounds titles made of a few very long words
+const maxTitleRunes = 40
+
+// GenerateTitle asks the model for a 3-5 word title for a conversation opening with input
+func (c *OpenAIClient) GenerateTitle(input string) (string, error) {
+	title, err := c.Complete(
+		"Write a title of 3 to 5 words for a conversation that starts with the following message. Reply with the title only, without quotes or punctuation at the end.",
+		input,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate title: %w", err)
+	}
+	title = strings.Trim(strings.TrimSpace(title), `"'.`)
+	if title == "" {
+		return "", fmt.Errorf("failed to generate title: empty reply")
+	}
+	return TitleFromInput(title), nil
+}
+
+// TitleFromInput derives a title from the first few words of input
+func TitleFromInput(input string) string {
+	words := strings.Fields(input)
+	if len(words) > maxTitleWords {
+		return models.Truncate(strings.Join(words[:maxTitleWords], " ")+"…", maxTitleRunes)
+	}
+	return models.Truncate(strings.Join(words, " "), maxTitleRunes)
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// topicWindow is the number of recent turns a new input is compared against
+const topicWindow = 3
+
+// minTopicKeywords is the number of keywords an input needs before it is judged at all;
+// short follow-ups like "what about the second one?" share few words but aren't new topics
+const minTopicKeywords = 4
+
+// topicOverlapThreshold is the share of an input's keywords below which it counts as a new topic
+const topicOverlapThreshold = 0.1
+
+// topicStopWords are common words that say nothing about the topic
+var topicStopWords = map[string]bool{
+	"about": true, "after": true, "also": true, "because": true, "been": true, "before": true,
+	"being": true, "could": true, "does": true, "from": true, "have": true, "into": true,
+	"just": true, "like": true, "more": true, "much": true, "only": true, "other": true,
+	"really": true, "should": true, "some": true, "than": true, "that": true, "their": true,
+	"them": true, "then": true, "there": true, "these": true, "they": true, "thing": true,
+	"this": true, "those": true, "very": true, "were": true, "what": true, "when": true,
+	"where": true, "which": true, "while": true, "will": true, "with": true, "would": true,
+	"your": true, "explain": true, "know": true, "think": true, "want": true,
+}
+
+// IsTopicChange reports whether input shares almost no keywords with the most recent
+// included turns, suggesting the earlier context no longer applies
+func IsTopicChange(input string, history []models.ConversationEntry) bool {
+	words := topicKeywords(input)
+	if len(words) < minTopicKeywords {
+		return false
+	}
+
+	recent := make(map[string]bool)
+	turns := 0
+	for i := len(history) - 1; i >= 0 && turns < topicWindow; i-- {
+		if history[i].Excluded {
+			continue
+		}
+		for word := range topicKeywords(history[i].UserInput + " " + history[i].AIResponse) {
+			recent[word] = true
+		}
+		turns++
+	}
+	if turns == 0 {
+		return false
+	}
+
+	shared := 0
+	for word := range words {
+		if recent[word] {
+			shared++
+		}
+	}
+	return float64(shared)/float64(len(words)) < topicOverlapThreshold
+}
+
+// topicKeywords returns the distinct lowercased content words of text, with plural s trimmed
+func topicKeywords(text string) map[string]bool {
+	keywords := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) < 4 || topicStopWords[word] {
+			continue
+		}
+		keywords[strings.TrimSuffix(word, "s")] = true
+	}
+	return keywords
+}
@@ -14,26 +14,87 @@ import (
 // TTSClient handles text-to-speech conversion using OpenAI
 type TTSClient struct {
 	client *openai.Client
+	apiKey string
 	model  string
 	voice  string
 	speed  float32
+	format openai.SpeechResponseFormat
+}
+
+// QualityProfile is a TTS output format and, if it needs one, the model to use with it
+type QualityProfile struct {
+	Format openai.SpeechResponseFormat
+	Model  string
+}
+
+// AudioQualityProfile returns the profile for a quality name: "low" is low-bitrate opus from tts-1
+// for the fastest first audio, "high" is MP3 from tts-1-hd, and anything else is MP3 from the configured model
+func AudioQualityProfile(quality string) QualityProfile {
+	switch quality {
+	case "low":
+		return QualityProfile{Format: openai.SpeechResponseFormatOpus, Model: "tts-1"}
+	case "high":
+		return QualityProfile{Format: openai.SpeechResponseFormatMp3, Model: "tts-1-hd"}
+	default:
+		return QualityProfile{Format: openai.SpeechResponseFormatMp3}
+	}
 }
 
 // NewTTSClient creates a new TTS client
 func NewTTSClient(apiKey, model, voice string) *TTSClient {
 	return &TTSClient{
 		client: openai.NewClient(apiKey),
+		apiKey: apiKey,
 		model:  model,
 		voice:  voice,
 		speed:  1.0,
 	}
 }
 
+// SetAccount routes the client's requests to an OpenAI organization and project
+func (t *TTSClient) SetAccount(account Account) {
+	t.client = newSDKClient(t.apiKey, account)
+}
+
+// SetQuality switches the client to the output format and model of a quality profile
+func (t *TTSClient) SetQuality(quality string) {
+	profile := AudioQualityProfile(quality)
+	t.format = profile.Format
+	if profile.Model != "" {
+		t.model = profile.Model
+	}
+}
+
+// Extension returns the file extension of the audio the client produces
+func (t *TTSClient) Extension() string {
+	switch t.format {
+	case openai.SpeechResponseFormatOpus:
+		return ".opus"
+	case openai.SpeechResponseFormatWav:
+		return ".wav"
+	}
+	return ".mp3"
+}
+
+// SetMP3Output switches the client back to MP3 output, for uses that need MP3 whatever the
+// quality profile or playback fallback picked
+func (t *TTSClient) SetMP3Output() {
+	t.format = openai.SpeechResponseFormatMp3
+}
+
+// SetWAVOutput switches the client to uncompressed WAV output, which plays without an
+// external decoder
+func (t *TTSClient) SetWAVOutput() {
+	t.format = openai.SpeechResponseFormatWav
+}
+
 // SetVoice updates the TTS client's voice.
 func (t *TTSClient) SetVoice(voice string) {
 	t.voice = voice
 }
 
+// SetSpeed sets the speaking rate from the 1 (slow), 2 (normal), 3 (fast) setting;
+// values outside 1-3 are treated as normal speed
 func (t *TTSClient) SetSpeed(speed int) {
 	switch speed {
 	case 1:
@@ -45,11 +106,11 @@ func (t *TTSClient) SetSpeed(speed int) {
 	}
 }
 
-// TextToSpeech converts text to audio and saves it to a file
-func (t *TTSClient) TextToSpeech(text string, outputPath string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// speechTimeout bounds a speech request, including downloading the audio
+const speechTimeout = 30 * time.Second
 
+// speechRequest builds the TTS request for text with the client's model, voice, speed and format
+func (t *TTSClient) speechRequest(text string) openai.CreateSpeechRequest {
 	// Create the TTS request
 	req := openai.CreateSpeechRequest{
 		Model: openai.SpeechModel(t.model),
@@ -75,9 +136,21 @@ func (t *TTSClient) TextToSpeech(text string, outputPath string) error {
 		req.Voice = openai.VoiceAlloy
 	}
 	req.Speed = float64(t.speed)
+	req.ResponseFormat = t.format
+	return req
+}
+
+// TextToSpeech converts text to audio and saves it to a file
+func (t *TTSClient) TextToSpeech(text string, outputPath string) error {
+	if t.apiKey == "" {
+		return ErrNoAPIKey
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), speechTimeout)
+	defer cancel()
 
 	// Make the request
-	response, err := t.client.CreateSpeech(ctx, req)
+	response, err := t.client.CreateSpeech(ctx, t.speechRequest(text))
 	if err != nil {
 		return fmt.Errorf("failed to create speech: %w", err)
 	}
@@ -93,19 +166,68 @@ func (t *TTSClient) TextToSpeech(text string, outputPath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
-	defer file.Close()
 
 	// Copy the audio data to the file
 	_, err = io.Copy(file, response)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
+		os.Remove(outputPath)
 		return fmt.Errorf("failed to write audio data: %w", err)
 	}
 
+	// Don't leave an interrupted or garbled download around for the player
+	if err := validateAudioFile(outputPath); err != nil {
+		os.Remove(outputPath)
+		return err
+	}
+
 	return nil
 }
 
+// TextToSpeechStream converts text to audio and returns it as it downloads, in the format
+// Extension names, so playback can start before the whole response has arrived.
+// The caller must close the stream.
+func (t *TTSClient) TextToSpeechStream(text string) (io.ReadCloser, error) {
+	if t.apiKey == "" {
+		return nil, ErrNoAPIKey
+	}
+
+	// The timeout only covers getting a response: the player reads the audio in real time,
+	// so downloading a long reply can outlast it
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := time.AfterFunc(speechTimeout, cancel)
+	response, err := t.client.CreateSpeech(ctx, t.speechRequest(text))
+	if !timer.Stop() && err == nil {
+		// Timed out just as the response arrived, so reading it would fail
+		response.Close()
+		err = context.DeadlineExceeded
+	}
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create speech: %w", err)
+	}
+	return &speechStream{ReadCloser: response, cancel: cancel}, nil
+}
+
+// speechStream is a speech download whose request is released when it is closed
+type speechStream struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (s *speechStream) Close() error {
+	defer s.cancel()
+	return s.ReadCloser.Close()
+}
+
 // ValidateAPIKey checks if the OpenAI API key is valid
 func (t *TTSClient) ValidateAPIKey() error {
+	if t.apiKey == "" {
+		return ErrNoAPIKey
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -1,10 +1,13 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -12,17 +15,26 @@ import (
 	"github.com/jorkle/jork/internal/audio"
 	"github.com/jorkle/jork/internal/config"
 	"github.com/jorkle/jork/internal/models"
+	"github.com/jorkle/jork/pkg/engine"
 )
 
 // App represents the main application
 type App struct {
 	config       *config.Config
+	engine       *engine.Engine
 	openaiClient *ai.OpenAIClient
 	ttsClient    *ai.TTSClient
 	sttClient    *ai.STTClient
-	recorder     *audio.Recorder
+	recorder     audio.AudioRecorder
 	player       *audio.Player
 	state        *models.AppState
+
+	summaryMutex sync.Mutex
+	summary      string // last session summary
+	summaryTurns int    // number of logged turns the summary covers
+
+	healthMutex sync.Mutex
+	lastHealth  *HealthReport // nil until the first health check
 }
 
 // NewApp creates a new application instance
@@ -32,58 +44,107 @@ func NewApp() (*App, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Initialize AI clients
-	openaiClient := ai.NewOpenAIClient(cfg.OpenAIAPIKey, cfg.ConversationModel)
-	ttsClient := ai.NewTTSClient(cfg.OpenAIAPIKey, cfg.OpenAITTSModel, cfg.OpenAITTSVoice)
-	sttClient := ai.NewSTTClient(cfg.OpenAIAPIKey, cfg.OpenAISTTModel)
+	app := &App{
+		config: cfg,
+		player: audio.NewPlayer(),
+	}
+	if err := app.configure(); err != nil {
+		return nil, err
+	}
 
-	// Initialize audio components
-	recorder, err := audio.NewRecorder(cfg.SampleRate, 1) // mono
-	if err != nil {
-		return nil, fmt.Errorf("failed to create audio recorder: %w", err)
+	if cfg.WipeAudioTempOnStart {
+		if err := app.wipeTempDir(); err != nil {
+			log.Printf("Error wiping audio temp directory: %v", err)
+		}
+	}
+	if err := app.EnforceDiskUsage(); err != nil {
+		log.Printf("Error enforcing disk usage cap: %v", err)
 	}
+	if !cfg.Locked() {
+		if err := app.LoadConversationLog(); err != nil {
+			log.Printf("Error loading conversation log: %v", err)
+		}
+	}
+
+	return app, nil
+}
 
-	player := audio.NewPlayer()
+// configure builds the engine and recorder from the settings and fits speech output to the
+// installed players. It runs once the settings are loaded, and again when encrypted ones are
+// unlocked, since until then the settings hold only defaults.
+func (a *App) configure() error {
+	var recorder audio.AudioRecorder
+	var err error
+	if a.config.AudioInputFile != "" {
+		recorder, err = audio.NewFileRecorder(a.config.AudioInputFile)
+	} else {
+		recorder, err = audio.NewRecorder(a.config.SampleRate, a.config.RecordChannels, a.config.InputDevice)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create audio recorder: %w", err)
+	}
+	if mic, ok := recorder.(*audio.Recorder); ok {
+		mic.EnableVADAutoStop(a.config.SilenceThreshold, time.Duration(a.config.SilenceStopSeconds*float64(time.Second)))
+	}
+	if a.recorder != nil {
+		if err := a.recorder.Close(); err != nil {
+			log.Printf("Error closing audio recorder: %v", err)
+		}
+	}
+	a.recorder = recorder
 
-	// Initialize app state
-	state := &models.AppState{
-		CurrentMode:     cfg.DefaultMode,
-		KnowledgeLevel:  cfg.DefaultKnowledgeLevel,
-		IsRecording:     false,
-		IsPlaying:       false,
-		IsProcessing:    false,
-		ConversationLog: make([]models.ConversationEntry, 0),
+	// Audio saved for playback goes with the rest, where wiping and the disk cap can find it
+	if dir, err := a.config.AudioTempPath(""); err == nil {
+		a.player.SetTempDir(dir)
+	} else {
+		log.Printf("Error preparing audio temp directory: %v", err)
 	}
 
-	return &App{
-		config:       cfg,
-		openaiClient: openaiClient,
-		ttsClient:    ttsClient,
-		sttClient:    sttClient,
-		recorder:     recorder,
-		player:       player,
-		state:        state,
-	}, nil
+	if a.engine != nil {
+		if err := a.engine.Close(); err != nil {
+			log.Printf("Error closing engine: %v", err)
+		}
+	}
+	a.engine = engine.New(a.config)
+	a.openaiClient = a.engine.ChatClient()
+	a.ttsClient = a.engine.TTSClient()
+	a.sttClient = a.engine.STTClient()
+	a.state = a.engine.State()
+
+	if format := strings.TrimPrefix(a.ttsClient.Extension(), "."); !a.player.CanPlay(format) && a.player.CanPlay("wav") {
+		// PortAudio plays WAV without any player installed, so speech still works out of the box
+		log.Printf("No %s player found, synthesizing speech as WAV for the built-in player", format)
+		a.ttsClient.SetWAVOutput()
+	}
+	return nil
 }
 
 // Run starts the application
 func (a *App) Run() error {
-	// Validate API keys
-	if err := a.openaiClient.ValidateAPIKey(); err != nil {
-		return fmt.Errorf("invalid OpenAI API key: %w", err)
-	}
-
-	if err := a.ttsClient.ValidateAPIKey(); err != nil {
-		return fmt.Errorf("invalid OpenAI TTS API key: %w", err)
-	}
+	// Encrypted settings hold the keys, so checks wait until the TUI has unlocked them
+	if !a.config.Locked() {
+		// Validate API keys
+		if err := a.engine.Validate(); err != nil {
+			return err
+		}
 
-	if err := a.sttClient.ValidateAPIKey(); err != nil {
-		return fmt.Errorf("invalid OpenAI STT API key: %w", err)
+		// Warm up cold backends in the background so the first turn is fast
+		if a.config.WarmupOnStart {
+			go a.engine.Warmup()
+		}
 	}
 
 	// Create and run the Bubbletea program
 	model := NewModel(a)
-	program := tea.NewProgram(model, tea.WithAltScreen())
+	var opts []tea.ProgramOption
+	if !a.config.NoAltScreen {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	if !a.config.NoMouse {
+		// Wheel events scroll the history and conversation views
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	program := tea.NewProgram(model, opts...)
 
 	if _, err := program.Run(); err != nil {
 		return fmt.Errorf("failed to run program: %w", err)
@@ -92,83 +153,71 @@ func (a *App) Run() error {
 	return nil
 }
 
-// ProcessTextInput processes text input and returns AI response
-func (a *App) ProcessTextInput(input string) (string, error) {
-	a.state.IsProcessing = true
-	defer func() { a.state.IsProcessing = false }()
-
-	// Generate response using OpenAI
-	response, err := a.openaiClient.GenerateResponse(
-		input,
-		a.state.KnowledgeLevel,
-		a.state.CurrentMode,
-		a.state.ConversationLog,
-		"general", // topic - could be made configurable
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate response: %w", err)
+// Unlock opens encrypted settings with passphrase, rebuilds the engine and recorder from them
+// and checks the API keys they hold, which Run couldn't while they were locked. The settings
+// stay unlocked when only the key check fails.
+func (a *App) Unlock(passphrase string) error {
+	if err := a.config.Unlock(passphrase); err != nil {
+		return err
 	}
-
-	// Log the conversation
-	entry := models.ConversationEntry{
-		Timestamp:      time.Now(),
-		UserInput:      input,
-		AIResponse:     response,
-		Mode:           a.state.CurrentMode,
-		KnowledgeLevel: a.state.KnowledgeLevel,
-		IsVoiceInput:   a.state.CurrentMode == models.VoiceToText || a.state.CurrentMode == models.VoiceToVoice,
-		IsVoiceOutput:  a.state.CurrentMode == models.TextToVoice || a.state.CurrentMode == models.VoiceToVoice,
+	if err := a.configure(); err != nil {
+		return err
+	}
+	if err := a.LoadConversationLog(); err != nil {
+		log.Printf("Error loading conversation log: %v", err)
+	}
+	if err := a.engine.Validate(); err != nil {
+		return err
 	}
+	if a.config.WarmupOnStart {
+		go a.engine.Warmup()
+	}
+	return nil
+}
 
-	a.state.ConversationLog = append(a.state.ConversationLog, entry)
+// ProcessTextInput processes text input and returns AI response
+func (a *App) ProcessTextInput(input string) (string, error) {
+	return a.engine.ProcessTextInput(input)
+}
 
-	// Keep only the last N entries
-	if len(a.state.ConversationLog) > a.config.MaxConversationHistory {
-		a.state.ConversationLog = a.state.ConversationLog[len(a.state.ConversationLog)-a.config.MaxConversationHistory:]
-	}
+// ProcessEditedTextInput processes input edited from the entry logged at editedFrom
+func (a *App) ProcessEditedTextInput(ctx context.Context, input string, editedFrom time.Time) (string, error) {
+	return a.engine.ProcessEditedTextInput(ctx, input, editedFrom)
+}
 
-	a.state.LastMessage = input
-	a.state.LastResponse = response
+// RegenerateLast replaces the reply to the last logged input with a new one
+func (a *App) RegenerateLast(ctx context.Context) (string, error) {
+	return a.engine.RegenerateLast(ctx)
+}
 
-	return response, nil
+// LoadConversationLog restores the conversation log saved by earlier sessions
+func (a *App) LoadConversationLog() error {
+	return a.engine.LoadConversationLog()
 }
 
-// ProcessVoiceInput processes voice input and returns appropriate response
-func (a *App) ProcessVoiceInput(audioData *models.AudioData) (string, error) {
-	a.state.IsProcessing = true
-	defer func() { a.state.IsProcessing = false }()
+// SaveConversationLog writes the conversation log to Config.LogFile
+func (a *App) SaveConversationLog() error {
+	return a.engine.SaveConversationLog()
+}
 
+// ProcessVoiceInput processes voice input and returns appropriate response
+func (a *App) ProcessVoiceInput(ctx context.Context, audioData *models.AudioData) (string, error) {
 	// Save audio to temporary file for processing
-	tempFile := filepath.Join(a.config.AudioTempDir, fmt.Sprintf("input_%d.wav", time.Now().Unix()))
-	if err := a.recorder.SaveToWAV(audioData, tempFile); err != nil {
+	tempFile, err := a.config.AudioTempPath(fmt.Sprintf("input_%d.wav", time.Now().Unix()))
+	if err != nil {
+		return "", err
+	}
+	if err := a.recorder.SaveToWAV(audio.DownmixToMono(audioData), tempFile); err != nil {
 		return "", fmt.Errorf("failed to save audio: %w", err)
 	}
 	defer os.Remove(tempFile)
 
-	// Convert speech to text using OpenAI Whisper
-	transcription, err := a.sttClient.SpeechToText(tempFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to transcribe audio: %w", err)
-	}
-
-	// Process the transcription as text
-	return a.ProcessTextInput(transcription)
+	return a.engine.ProcessVoiceFile(ctx, tempFile)
 }
 
 // GenerateVoiceResponse converts text response to speech
 func (a *App) GenerateVoiceResponse(text string) (string, error) {
-	a.state.IsProcessing = true
-	defer func() { a.state.IsProcessing = false }()
-
-	// Generate unique filename
-	filename := filepath.Join(a.config.AudioTempDir, fmt.Sprintf("response_%d.mp3", time.Now().Unix()))
-
-	// Convert text to speech
-	if err := a.ttsClient.TextToSpeech(text, filename); err != nil {
-		return "", fmt.Errorf("failed to generate speech: %w", err)
-	}
-
-	return filename, nil
+	return a.engine.GenerateVoiceResponse(text)
 }
 
 // StartRecording starts audio recording
@@ -185,6 +234,57 @@ func (a *App) StartRecording() error {
 	return nil
 }
 
+// SetInputDevice selects the microphone to record from, by name
+func (a *App) SetInputDevice(name string) {
+	a.config.InputDevice = name
+	if recorder, ok := a.recorder.(*audio.Recorder); ok {
+		recorder.SetDevice(name)
+	}
+}
+
+// SetSTTLanguage sets the language recordings are transcribed as, empty to auto-detect
+func (a *App) SetSTTLanguage(language string) {
+	a.config.STTLanguage = language
+	a.sttClient.SetLanguage(language)
+}
+
+// InputLevel returns the amplitude of the audio being recorded, from 0 to 1
+func (a *App) InputLevel() float32 {
+	return a.recorder.CurrentLevel()
+}
+
+// AutoStop returns a channel that receives true when silence ends the current recording.
+// It is nil when recordings only stop on request.
+func (a *App) AutoStop() <-chan bool {
+	if recorder, ok := a.recorder.(*audio.Recorder); ok && a.config.SilenceStopSeconds > 0 {
+		return recorder.AutoStop()
+	}
+	return nil
+}
+
+// PauseRecording pauses or resumes the current recording; only microphone recordings can pause
+func (a *App) PauseRecording(paused bool) error {
+	recorder, ok := a.recorder.(*audio.Recorder)
+	if !ok {
+		return fmt.Errorf("this recording can't be paused")
+	}
+	if paused {
+		recorder.Pause()
+	} else {
+		recorder.Resume()
+	}
+	return nil
+}
+
+// RecordingWarning returns a problem with the current recording that didn't stop it,
+// such as the configured input device being missing
+func (a *App) RecordingWarning() string {
+	if recorder, ok := a.recorder.(*audio.Recorder); ok {
+		return recorder.Warning()
+	}
+	return ""
+}
+
 // StopRecording stops audio recording and returns the recorded data
 func (a *App) StopRecording() (*models.AudioData, error) {
 	if !a.state.IsRecording {
@@ -213,6 +313,10 @@ func (a *App) PlayAudio(filename string) error {
 		if err := a.player.PlayMP3File(filename); err != nil {
 			return fmt.Errorf("failed to play MP3: %w", err)
 		}
+	case ".opus":
+		if err := a.player.PlayOpusFile(filename); err != nil {
+			return fmt.Errorf("failed to play Opus: %w", err)
+		}
 	case ".wav":
 		if err := a.player.PlayFile(filename); err != nil {
 			return fmt.Errorf("failed to play WAV: %w", err)
@@ -232,6 +336,44 @@ func (a *App) PlayAudio(filename string) error {
 	return nil
 }
 
+// StreamVoiceResponse synthesizes text and starts playing the speech as it downloads
+func (a *App) StreamVoiceResponse(text string) error {
+	stream, err := a.engine.StreamVoiceResponse(text)
+	if err != nil {
+		return err
+	}
+	format := strings.TrimPrefix(a.ttsClient.Extension(), ".")
+	if err := a.player.StreamAudioFromReader(stream, format); err != nil {
+		return fmt.Errorf("failed to play speech: %w", err)
+	}
+
+	a.state.IsPlaying = true
+	go func() {
+		a.player.WaitForPlayback()
+		a.state.IsPlaying = false
+	}()
+	return nil
+}
+
+// PlayRecording plays back recorded audio, stopping anything already playing
+func (a *App) PlayRecording(audioData *models.AudioData) error {
+	if a.player.IsPlaying() {
+		if err := a.player.StopPlayback(); err != nil {
+			return err
+		}
+	}
+	if err := a.player.PlayAudioData(audioData); err != nil {
+		return fmt.Errorf("failed to play recording: %w", err)
+	}
+
+	a.state.IsPlaying = true
+	go func() {
+		a.player.WaitForPlayback()
+		a.state.IsPlaying = false
+	}()
+	return nil
+}
+
 // StopAudio stops current audio playback
 func (a *App) StopAudio() error {
 	if !a.state.IsPlaying {
@@ -247,16 +389,31 @@ func (a *App) StopAudio() error {
 }
 
 // PlayAudioSample generates and plays a sample TTS audio using the current TTS settings.
+// Any audio already playing is stopped first. Samples are kept per voice, model and speed,
+// so switching back to a setting replays its sample without synthesizing it again.
 func (a *App) PlayAudioSample() error {
+	if a.player.IsPlaying() {
+		if err := a.player.StopPlayback(); err != nil {
+			return err
+		}
+		a.state.IsPlaying = false
+	}
+
 	sampleText := "This is a sample voice from the selected TTS configuration."
-	filename := filepath.Join(a.config.AudioTempDir, "sample_voice.mp3")
-	// Update TTS client voice and speed to current settings using exported methods
-	a.ttsClient.SetVoice(a.config.TTSTargetVoice)
-	a.ttsClient.SetSpeed(a.config.SpeechSpeed)
-	if err := a.ttsClient.TextToSpeech(sampleText, filename); err != nil {
-		return fmt.Errorf("failed to generate TTS sample: %w", err)
+	name := fmt.Sprintf("sample_%s_%s_%d%s", a.config.TTSTargetVoice, a.config.TTSTargetModel, a.config.SpeechSpeed, a.ttsClient.Extension())
+	filename, err := a.config.AudioTempPath(name)
+	if err != nil {
+		return err
 	}
-	return a.player.PlayMP3File(filename)
+	if _, err := os.Stat(filename); err != nil {
+		// Update TTS client voice and speed to current settings using exported methods
+		a.ttsClient.SetVoice(a.config.TTSTargetVoice)
+		a.ttsClient.SetSpeed(a.config.SpeechSpeed)
+		if err := a.ttsClient.TextToSpeech(sampleText, filename); err != nil {
+			return fmt.Errorf("failed to generate TTS sample: %w", err)
+		}
+	}
+	return a.PlayAudio(filename)
 }
 
 // GenerateExplanationSample creates a sample explanation using the current knowledge level.
@@ -267,18 +424,88 @@ func (a *App) GenerateExplanationSample() (string, error) {
 		a.state.KnowledgeLevel,
 		a.state.CurrentMode,
 		a.state.ConversationLog,
-		"general",
+		a.engine.Topic(),
 	)
 }
 
+// CompareLevels answers the same input at two knowledge levels without using the conversation history
+func (a *App) CompareLevels(input string, first, second models.KnowledgeLevel) (string, string, error) {
+	levels := []models.KnowledgeLevel{first, second}
+	responses := make([]string, len(levels))
+	errs := make([]error, len(levels))
+	runBounded(len(levels), a.config.BatchConcurrency, func(i int) {
+		responses[i], errs[i] = a.openaiClient.GenerateResponse(input, levels[i], models.TextToText, nil, a.engine.Topic())
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate %s response: %w", levels[i].String(), err)
+		}
+	}
+	return responses[0], responses[1], nil
+}
+
+// newTTSClient creates a TTS client for voice set up like the engine's: the current TTS model,
+// quality profile, speed and account, producing the same format
+func (a *App) newTTSClient(voice string) *ai.TTSClient {
+	tts := ai.NewTTSClient(a.config.OpenAIAPIKey, a.config.TTSTargetModel, voice)
+	tts.SetAccount(a.engine.Account())
+	tts.SetQuality(a.config.AudioQuality)
+	tts.SetSpeed(a.config.SpeechSpeed)
+	if a.ttsClient.Extension() == ".wav" {
+		tts.SetWAVOutput()
+	}
+	return tts
+}
+
+// CompareVoices synthesizes a sample for every TTS voice and plays them in order.
+// Synthesis runs BatchConcurrency at a time; each sample plays as soon as it and all earlier ones are ready.
+func (a *App) CompareVoices() error {
+	voices := a.ttsClient.GetAvailableVoices()
+	files := make([]string, len(voices))
+	errs := make([]error, len(voices))
+	ready := make([]chan struct{}, len(voices))
+	for i := range ready {
+		ready[i] = make(chan struct{})
+	}
+
+	go runBounded(len(voices), a.config.BatchConcurrency, func(i int) {
+		defer close(ready[i])
+		tts := a.newTTSClient(voices[i])
+		if files[i], errs[i] = a.config.AudioTempPath(fmt.Sprintf("compare_%s%s", voices[i], tts.Extension())); errs[i] != nil {
+			return
+		}
+		errs[i] = tts.TextToSpeech(fmt.Sprintf("This is the %s voice.", voices[i]), files[i])
+	})
+
+	var firstErr error
+	for i := range voices {
+		<-ready[i]
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to synthesize %s sample: %w", voices[i], errs[i])
+			}
+			continue
+		}
+		if err := a.PlayAudio(files[i]); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to play %s sample: %w", voices[i], err)
+			}
+			continue
+		}
+		a.player.WaitForPlayback()
+	}
+	return firstErr
+}
+
 // SetMode changes the communication mode
 func (a *App) SetMode(mode models.CommunicationMode) {
-	a.state.CurrentMode = mode
+	a.engine.SetMode(mode)
 }
 
 // SetKnowledgeLevel changes the knowledge level
 func (a *App) SetKnowledgeLevel(level models.KnowledgeLevel) {
-	a.state.KnowledgeLevel = level
+	a.engine.SetKnowledgeLevel(level)
 }
 
 // GetState returns the current application state
@@ -286,6 +513,11 @@ func (a *App) GetState() *models.AppState {
 	return a.state
 }
 
+// ConfigDir returns the directory jork keeps its settings and logs in
+func (a *App) ConfigDir() string {
+	return a.config.ConfigDir
+}
+
 // Cleanup performs cleanup operations
 func (a *App) Cleanup() error {
 	// Stop any ongoing recording
@@ -307,35 +539,59 @@ func (a *App) Cleanup() error {
 	if err := a.cleanupTempFiles(); err != nil {
 		log.Printf("Error cleaning up temp files: %v", err)
 	}
+	if err := a.EnforceDiskUsage(); err != nil {
+		log.Printf("Error enforcing disk usage cap: %v", err)
+	}
+
+	if err := a.engine.Close(); err != nil {
+		log.Printf("Error closing engine: %v", err)
+	}
 
 	return nil
 }
 
-// cleanupTempFiles removes temporary audio files
+// cleanupTempFiles removes temporary audio files older than an hour
 func (a *App) cleanupTempFiles() error {
-	return filepath.Walk(a.config.AudioTempDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return a.removeTempFiles(func(info os.FileInfo) bool {
+		return time.Since(info.ModTime()) > time.Hour
+	})
+}
 
-		if !info.IsDir() {
-			// Remove files older than 1 hour
-			if time.Since(info.ModTime()) > time.Hour {
-				return os.Remove(path)
-			}
+// wipeTempDir removes every file in the audio temp directory.
+// Directories outside the config dir are only wiped when explicitly allowed.
+func (a *App) wipeTempDir() error {
+	if !a.config.IsWithinConfigDir(a.config.AudioTempDir) && !a.config.AllowExternalTempDir {
+		return fmt.Errorf("refusing to wipe %s: outside config directory %s", a.config.AudioTempDir, a.config.ConfigDir)
+	}
+	return a.removeTempFiles(func(os.FileInfo) bool { return true })
+}
+
+// removeTempFiles removes the regular files in the audio temp directory matching shouldRemove.
+// Individual failures don't stop the sweep; the first one is returned.
+func (a *App) removeTempFiles(shouldRemove func(os.FileInfo) bool) error {
+	entries, err := os.ReadDir(a.config.AudioTempDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
 		}
+		return err
+	}
 
-		return nil
-	})
-}
-	
-func (a *App) HealthCheck() error {
-	// Use a simple "health check" prompt.
-	_, err := a.openaiClient.GenerateResponse("health check", models.CoWorker, a.state.CurrentMode, a.state.ConversationLog, "health")
-	if err == nil {
-		if modelsList, err2 := a.openaiClient.FetchAvailableModels(); err2 == nil {
-			a.config.AvailableModels = modelsList
+	var firstErr error
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if !shouldRemove(info) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(a.config.AudioTempDir, entry.Name())); err != nil && firstErr == nil {
+			firstErr = err
 		}
 	}
-	return err
+	return firstErr
 }
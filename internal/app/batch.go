@@ -0,0 +1,24 @@
+package app
+
+import "sync"
+
+// runBounded calls fn for every index in [0, n) with at most limit calls in flight.
+// Callers store results by index so ordering is preserved regardless of completion order.
+func runBounded(n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
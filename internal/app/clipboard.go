@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// errNoClipboard is returned when no clipboard tool is available, as on a headless server
+var errNoClipboard = errors.New("no clipboard available; install wl-clipboard, xclip or xsel")
+
+// clipboardCommand is a program that copies its standard input to the system clipboard
+type clipboardCommand struct {
+	name string
+	args []string
+}
+
+// clipboardCommands returns the clipboard programs to try on this system, in order of preference
+func clipboardCommands() []clipboardCommand {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardCommand{{name: "pbcopy"}}
+	case "windows":
+		return []clipboardCommand{{name: "clip"}}
+	}
+	var commands []clipboardCommand
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, clipboardCommand{name: "wl-copy"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		commands = append(commands,
+			clipboardCommand{name: "xclip", args: []string{"-selection", "clipboard"}},
+			clipboardCommand{name: "xsel", args: []string{"--clipboard", "--input"}},
+		)
+	}
+	return commands
+}
+
+// copyToClipboard puts text on the system clipboard with the first clipboard program found
+func copyToClipboard(text string) error {
+	for _, c := range clipboardCommands() {
+		if _, err := exec.LookPath(c.name); err != nil {
+			continue
+		}
+		cmd := exec.Command(c.name, c.args...)
+		cmd.Stdin = strings.NewReader(text)
+		// Output isn't captured: xclip leaves a child holding the pipes to serve the selection
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s failed: %w", c.name, err)
+		}
+		return nil
+	}
+	return errNoClipboard
+}
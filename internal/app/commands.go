@@ -1,16 +1,21 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"time"
 	
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/jorkle/jork/internal/models"
+	"github.com/jorkle/jork/pkg/engine"
 )
 
 // Command messages for the Bubbletea application
 
 // RecordingStartedMsg indicates recording has started
-type RecordingStartedMsg struct{}
+type RecordingStartedMsg struct {
+	Warning string // a problem that didn't stop the recording, shown while recording
+}
 
 // RecordingStoppedMsg indicates recording has stopped
 type RecordingStoppedMsg struct {
@@ -23,8 +28,83 @@ type ProcessingStartedMsg struct{}
 
 // ProcessingCompletedMsg indicates AI processing has completed
 type ProcessingCompletedMsg struct {
-	Response string
-	Error    error
+	Response  string
+	Error     error
+	RequestID int
+	Timestamp time.Time // of the logged entry, zero if nothing was logged
+	Trimmed   bool      // history was trimmed to fit the context window
+	SpeechErr error     // synthesizing the spoken response failed
+	Speech    string    // text that was to be spoken, kept for a retry
+	AudioFile string    // synthesized speech whose playback was deferred, see speakResponse
+}
+
+// SpeechRetriedMsg reports the result of retrying synthesis of a response
+type SpeechRetriedMsg struct {
+	Error error
+}
+
+// responseChunkMsg carries the next piece of a streaming reply
+type responseChunkMsg struct {
+	requestID int
+	text      string
+	updates   <-chan tea.Msg
+}
+
+// processingCancelledMsg indicates the user abandoned an in-flight request
+type processingCancelledMsg struct {
+	requestID int
+}
+
+// ComparisonCompletedMsg carries the responses of a knowledge level comparison
+type ComparisonCompletedMsg struct {
+	First     string
+	Second    string
+	Error     error
+	RequestID int
+}
+
+// ExportProgressMsg reports how many clips of a conversation audio export are synthesized
+type ExportProgressMsg struct {
+	Done    int
+	Total   int
+	updates <-chan tea.Msg
+}
+
+// ExportCompletedMsg carries the path of a finished conversation audio export
+type ExportCompletedMsg struct {
+	Path  string
+	Error error
+}
+
+// OutputTestedMsg reports the result of playing the output test tone
+type OutputTestedMsg struct {
+	Backend string
+	Error   error
+}
+
+// ModelsRefreshedMsg carries a freshly fetched model list
+type ModelsRefreshedMsg struct {
+	Models []string
+	Error  error
+	Manual bool // requested from Settings rather than refreshed because the cache was stale
+}
+
+// SessionSummaryMsg carries an updated session summary
+type SessionSummaryMsg struct {
+	Summary string
+	Error   error
+}
+
+// HealthCheckedMsg carries the report of a finished health check
+type HealthCheckedMsg struct {
+	Report HealthReport
+	Models []string // refreshed by a passing chat check, nil otherwise
+}
+
+// ModeValidatedMsg reports whether the providers needed by a newly selected mode are usable
+type ModeValidatedMsg struct {
+	Mode  models.CommunicationMode
+	Error error
 }
 
 // AudioPlaybackStartedMsg indicates audio playback has started
@@ -41,7 +121,38 @@ func StartRecordingCmd(app *App) tea.Cmd {
 		if err := app.StartRecording(); err != nil {
 			return RecordingStartedMsg{} // Even if error, we tried to start
 		}
-		return RecordingStartedMsg{}
+		return RecordingStartedMsg{Warning: app.RecordingWarning()}
+	}
+}
+
+// ClipboardCopiedMsg reports the result of copying text to the clipboard
+type ClipboardCopiedMsg struct {
+	Error error
+}
+
+// CopyToClipboardCmd returns a command that puts text on the system clipboard
+func CopyToClipboardCmd(text string) tea.Cmd {
+	return func() tea.Msg {
+		return ClipboardCopiedMsg{Error: copyToClipboard(text)}
+	}
+}
+
+// silenceDetectedMsg reports that the recording session went quiet after speech
+type silenceDetectedMsg struct {
+	session int
+}
+
+// WaitForSilenceCmd returns a command that waits for autoStop to report silence in the
+// recording session; it ends without a message if the recording is stopped first
+func WaitForSilenceCmd(autoStop <-chan bool, session int) tea.Cmd {
+	if autoStop == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if <-autoStop {
+			return silenceDetectedMsg{session: session}
+		}
+		return nil
 	}
 }
 
@@ -56,44 +167,115 @@ func StopRecordingCmd(app *App) tea.Cmd {
 	}
 }
 
-// ProcessTextCmd returns a command to process text input
-func ProcessTextCmd(app *App, input string) tea.Cmd {
+// ProcessTextCmd returns a command to process text input; cancelling ctx aborts the request
+func ProcessTextCmd(ctx context.Context, app *App, input string) tea.Cmd {
+	return ProcessEditedTextCmd(ctx, app, input, time.Time{})
+}
+
+// ProcessEditedTextCmd returns a command to process input edited from the entry logged at editedFrom
+func ProcessEditedTextCmd(ctx context.Context, app *App, input string, editedFrom time.Time) tea.Cmd {
 	return func() tea.Msg {
-		// Run health check before starting conversation
-		if err := app.HealthCheck(); err != nil {
+		// Check the keys the mode needs before starting the turn
+		if err := app.engine.Validate(); err != nil {
 			return ProcessingCompletedMsg{
 				Response: "",
-				Error:    fmt.Errorf("Health check failed: %s", err.Error()),
+				Error:    fmt.Errorf("Health check failed: %w", err),
 			}
 		}
-		response, err := app.ProcessTextInput(input)
-		
-		// Handle voice output if needed
-		if err == nil && (app.state.CurrentMode == models.TextToVoice || app.state.CurrentMode == models.VoiceToVoice) {
-			if audioFile, audioErr := app.GenerateVoiceResponse(response); audioErr == nil {
-				go app.PlayAudio(audioFile) // Play in background
-			}
-		}
-		
-		return ProcessingCompletedMsg{
-			Response: response,
-			Error:    err,
+		if ctx.Err() != nil {
+			return ProcessingCompletedMsg{Error: engine.ErrCancelled}
 		}
+		response, err := app.ProcessEditedTextInput(ctx, input, editedFrom)
+		return completeTextTurn(app, response, err)
+	}
+}
+
+// StreamTextCmd is ProcessEditedTextCmd that sends the reply as responseChunkMsgs while it is
+// generated, followed by the ProcessingCompletedMsg. Cancelling ctx stops the stream.
+func StreamTextCmd(ctx context.Context, app *App, input string, editedFrom time.Time, requestID int) tea.Cmd {
+	return func() tea.Msg {
+		updates := make(chan tea.Msg)
+		go func() {
+			defer close(updates)
+			var msg ProcessingCompletedMsg
+			if err := app.engine.Validate(); err != nil {
+				msg = ProcessingCompletedMsg{Error: fmt.Errorf("Health check failed: %w", err)}
+			} else if ctx.Err() != nil {
+				msg = ProcessingCompletedMsg{Error: engine.ErrCancelled}
+			} else {
+				response, err := app.engine.StreamEditedTextInput(ctx, input, editedFrom, func(text string) {
+					updates <- responseChunkMsg{requestID: requestID, text: text, updates: updates}
+				})
+				msg = completeTextTurn(app, response, err)
+			}
+			msg.RequestID = requestID
+			updates <- msg
+		}()
+		return <-updates
+	}
+}
+
+// waitForStreamCmd waits for the next update from a streaming reply
+func waitForStreamCmd(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
 	}
 }
 
-// ProcessVoiceCmd returns a command to process voice input
-func ProcessVoiceCmd(app *App, audioData interface{}) tea.Cmd {
+// RegenerateCmd returns a command that replaces the reply to the last logged input
+func RegenerateCmd(ctx context.Context, app *App) tea.Cmd {
+	return func() tea.Msg {
+		response, err := app.RegenerateLast(ctx)
+		msg := completeTextTurn(app, response, err)
+		// The entry predates the request, so it mustn't be discarded if the request is cancelled
+		msg.Timestamp = time.Time{}
+		return msg
+	}
+}
+
+// completeTextTurn speaks a typed turn's response when the mode calls for it and reports the outcome
+func completeTextTurn(app *App, response string, err error) ProcessingCompletedMsg {
+	var timestamp time.Time
+	if entry, ok := app.engine.LastEntry(); ok && err == nil {
+		timestamp = entry.Timestamp
+	}
+
+	// Handle voice output if needed
+	var speech, audioFile string
+	var speechErr error
+	if err == nil && (app.state.CurrentMode == models.TextToVoice || app.state.CurrentMode == models.VoiceToVoice) {
+		speech = app.engine.SpeechText(response)
+		audioFile, speechErr = speakResponse(app, speech)
+	}
+
+	return ProcessingCompletedMsg{
+		Response:  response,
+		Error:     err,
+		Timestamp: timestamp,
+		Trimmed:   app.state.LastTurnTrimmed,
+		SpeechErr: speechErr,
+		Speech:    speech,
+		AudioFile: audioFile,
+	}
+}
+
+// ProcessVoiceCmd returns a command to process voice input; cancelling ctx aborts the request
+func ProcessVoiceCmd(ctx context.Context, app *App, audioData interface{}) tea.Cmd {
 	return func() tea.Msg {
 		// Type assertion to get the actual audio data
 		if data, ok := audioData.(*models.AudioData); ok {
-			response, err := app.ProcessVoiceInput(data)
+			response, err := app.ProcessVoiceInput(ctx, data)
+			var timestamp time.Time
+			if entry, ok := app.engine.LastEntry(); ok && err == nil {
+				timestamp = entry.Timestamp
+			}
 			
 			// Handle voice output if needed
+			var speech, audioFile string
+			var speechErr error
 			if err == nil && app.state.CurrentMode == models.VoiceToVoice {
-				if audioFile, audioErr := app.GenerateVoiceResponse(response); audioErr == nil {
-					go app.PlayAudio(audioFile) // Play in background
-				}
+				speech = app.engine.SpeechText(response)
+				audioFile, speechErr = speakResponse(app, speech)
 			}
 			
 			msgResponse := response
@@ -101,8 +283,13 @@ func ProcessVoiceCmd(app *App, audioData interface{}) tea.Cmd {
 				msgResponse = "[Voice response played]"
 			}
 			return ProcessingCompletedMsg{
-				Response: msgResponse,
-				Error:    err,
+				Response:  msgResponse,
+				Error:     err,
+				Timestamp: timestamp,
+				Trimmed:   app.state.LastTurnTrimmed,
+				SpeechErr: speechErr,
+				Speech:    speech,
+				AudioFile: audioFile,
 			}
 		}
 		return ProcessingCompletedMsg{
@@ -111,3 +298,107 @@ func ProcessVoiceCmd(app *App, audioData interface{}) tea.Cmd {
 		}
 	}
 }
+
+// ValidateModeCmd returns a command that validates the providers required by mode
+func ValidateModeCmd(app *App, mode models.CommunicationMode) tea.Cmd {
+	return func() tea.Msg {
+		return ModeValidatedMsg{
+			Mode:  mode,
+			Error: app.engine.ValidateForMode(mode),
+		}
+	}
+}
+
+// ExportAudioCmd exports the conversation as one audio file, reporting progress along the way
+func ExportAudioCmd(app *App) tea.Cmd {
+	updates := make(chan tea.Msg, 1)
+	go func() {
+		defer close(updates)
+		path, err := app.ExportConversationAudio(func(done, total int) {
+			updates <- ExportProgressMsg{Done: done, Total: total, updates: updates}
+		})
+		updates <- ExportCompletedMsg{Path: path, Error: err}
+	}()
+	return waitForExportCmd(updates)
+}
+
+// waitForExportCmd waits for the next update from a running export
+func waitForExportCmd(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
+	}
+}
+
+// RefreshModelsCmd fetches the provider's models and updates the cache
+func RefreshModelsCmd(app *App, manual bool) tea.Cmd {
+	return func() tea.Msg {
+		models, err := app.RefreshModels()
+		return ModelsRefreshedMsg{Models: models, Error: err, Manual: manual}
+	}
+}
+
+// HealthCheckCmd checks every service in the background
+func HealthCheckCmd(app *App) tea.Cmd {
+	return func() tea.Msg {
+		report, models := app.HealthCheck()
+		return HealthCheckedMsg{Report: report, Models: models}
+	}
+}
+
+// UpdateSessionSummaryCmd regenerates the session summary if turns were added
+func UpdateSessionSummaryCmd(app *App) tea.Cmd {
+	return func() tea.Msg {
+		summary, err := app.UpdateSessionSummary()
+		return SessionSummaryMsg{Summary: summary, Error: err}
+	}
+}
+
+// AnnounceErrorCmd speaks a short phrase for err in voice-only sessions
+func AnnounceErrorCmd(app *App, err error) tea.Cmd {
+	return func() tea.Msg {
+		app.AnnounceError(err)
+		return nil
+	}
+}
+
+// TestOutputCmd plays the output test tone
+func TestOutputCmd(app *App) tea.Cmd {
+	return func() tea.Msg {
+		backend, err := app.TestOutput()
+		return OutputTestedMsg{Backend: backend, Error: err}
+	}
+}
+
+// RetrySpeechCmd synthesizes and plays text again after a failed attempt
+func RetrySpeechCmd(app *App, text string) tea.Cmd {
+	return func() tea.Msg {
+		return SpeechRetriedMsg{Error: speakInBackground(app, text)}
+	}
+}
+
+// speakResponse synthesizes a response. It starts playing right away unless an autoplay delay
+// or prompt is configured, in which case the file is returned for the UI to play later.
+func speakResponse(app *App, text string) (string, error) {
+	if app.config.AutoplayDelaySeconds <= 0 && !app.config.AutoplayPrompt {
+		return "", speakInBackground(app, text)
+	}
+	return app.GenerateVoiceResponse(text)
+}
+
+// speakInBackground synthesizes text and starts playing it as it arrives, without waiting
+// for playback to finish
+func speakInBackground(app *App, text string) error {
+	return app.StreamVoiceResponse(text)
+}
+
+// CompareLevelsCmd returns a command to answer the last input at two knowledge levels
+func CompareLevelsCmd(app *App, input string, first, second models.KnowledgeLevel) tea.Cmd {
+	return func() tea.Msg {
+		firstResp, secondResp, err := app.CompareLevels(input, first, second)
+		return ComparisonCompletedMsg{
+			First:  firstResp,
+			Second: secondResp,
+			Error:  err,
+		}
+	}
+}
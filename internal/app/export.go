@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jorkle/jork/internal/audio"
+)
+
+// ExportConversationAudio narrates the conversation log into a single MP3 file and returns its path.
+// User turns are read in ExportUserVoice and responses in the TTS target voice; progress is called
+// after each clip is synthesized with the number finished and the total.
+func (a *App) ExportConversationAudio(progress func(done, total int)) (string, error) {
+	entries := a.engine.History()
+	if len(entries) == 0 {
+		return "", fmt.Errorf("no conversation to export")
+	}
+
+	type clip struct {
+		text  string
+		voice string
+	}
+	var clips []clip
+	for _, entry := range entries {
+		clips = append(clips,
+			clip{entry.UserInput, a.config.ExportUserVoice},
+			clip{entry.AIResponse, a.config.TTSTargetVoice},
+		)
+	}
+
+	stamp := time.Now().Unix()
+	files := make([]string, len(clips))
+	errs := make([]error, len(clips))
+	for i := range clips {
+		path, err := a.config.AudioTempPath(fmt.Sprintf("export_%d_%03d.mp3", stamp, i))
+		if err != nil {
+			return "", err
+		}
+		files[i] = path
+	}
+	defer func() {
+		for _, file := range files {
+			os.Remove(file)
+		}
+	}()
+
+	filters := a.engine.SpeechFilters()
+	var mutex sync.Mutex
+	finished := 0
+	runBounded(len(clips), a.config.BatchConcurrency, func(i int) {
+		defer func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			finished++
+			if progress != nil {
+				progress(finished, len(clips))
+			}
+		}()
+		tts := a.newTTSClient(clips[i].voice)
+		// The clips are joined as MP3 frames, whatever format playback uses
+		tts.SetMP3Output()
+		errs[i] = tts.TextToSpeech(filters.Apply(clips[i].text), files[i])
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			return "", fmt.Errorf("failed to synthesize turn %d: %w", i/2+1, err)
+		}
+	}
+
+	name := fmt.Sprintf("conversation_%d.mp3", stamp)
+	if slug := titleSlug(a.state.Title); slug != "" {
+		name = fmt.Sprintf("conversation_%s_%d.mp3", slug, stamp)
+	}
+	output := filepath.Join(a.config.ConfigDir, "exports", name)
+	tempDir, err := a.config.AudioTempPath("")
+	if err != nil {
+		return "", err
+	}
+	if err := audio.ConcatMP3Files(files, output, tempDir); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// titleSlug turns a session title into a lowercase, filename-safe form
+func titleSlug(title string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			slug.WriteRune(r)
+			dash = false
+		} else if !dash && slug.Len() > 0 {
+			slug.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(slug.String(), "-")
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RunOnce answers a single prompt without the TUI and writes the response to out, for
+// scripts and CI. In modes with voice output the response is also spoken.
+func (a *App) RunOnce(out io.Writer, prompt string) error {
+	if a.config.Locked() {
+		return fmt.Errorf("settings are encrypted; unlock them once in the interactive app")
+	}
+	mode := a.state.CurrentMode
+	if mode.UsesVoiceInput() {
+		return fmt.Errorf("%s mode needs the microphone and can't run headless", mode)
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return fmt.Errorf("no prompt given")
+	}
+	if err := a.engine.Validate(); err != nil {
+		return err
+	}
+
+	response, err := a.ProcessTextInput(prompt)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, response)
+
+	if mode.UsesVoiceOutput() {
+		audioFile, err := a.GenerateVoiceResponse(a.engine.SpeechText(response))
+		if err != nil {
+			return fmt.Errorf("failed to speak response: %w", err)
+		}
+		if err := a.PlayAudio(audioFile); err != nil {
+			return err
+		}
+		a.player.WaitForPlayback()
+	}
+	return nil
+}
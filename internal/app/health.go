@@ -0,0 +1,84 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ServiceHealth is the outcome of checking one service
+type ServiceHealth struct {
+	Name  string
+	Error error // nil when the service passed
+}
+
+// HealthReport is the result of a health check, one entry per service in a fixed order
+type HealthReport struct {
+	Services  []ServiceHealth
+	CheckedAt time.Time
+}
+
+// Healthy reports whether every service passed
+func (r HealthReport) Healthy() bool {
+	return r.Err() == nil
+}
+
+// Err returns the first failure, naming its service, or nil when every service passed
+func (r HealthReport) Err() error {
+	for _, s := range r.Services {
+		if s.Error != nil {
+			return fmt.Errorf("%s: %w", s.Name, s.Error)
+		}
+	}
+	return nil
+}
+
+// HealthCheck checks the chat, TTS and STT API keys concurrently and keeps the report for
+// LastHealth. A passing chat check also fetches the model list, which is returned for the
+// caller to apply; it is nil when the chat check failed or the list couldn't be fetched.
+func (a *App) HealthCheck() (HealthReport, []string) {
+	checks := []struct {
+		name     string
+		validate func() error
+	}{
+		{"Chat (" + a.config.ConversationModel + ")", a.openaiClient.ValidateAPIKey},
+		{"Text to speech (" + a.config.TTSTargetModel + ")", a.ttsClient.ValidateAPIKey},
+		{"Speech to text (" + a.config.STTTargetModel + ")", a.sttClient.ValidateAPIKey},
+	}
+
+	report := HealthReport{Services: make([]ServiceHealth, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Services[i] = ServiceHealth{Name: check.name, Error: check.validate()}
+		}()
+	}
+	wg.Wait()
+	report.CheckedAt = time.Now()
+
+	var modelsList []string
+	if report.Services[0].Error == nil {
+		var err error
+		if modelsList, err = a.RefreshModels(); err != nil && len(modelsList) == 0 {
+			log.Printf("Failed to refresh models: %v", err)
+		}
+	}
+
+	a.healthMutex.Lock()
+	a.lastHealth = &report
+	a.healthMutex.Unlock()
+	return report, modelsList
+}
+
+// LastHealth returns the report of the most recent health check, and false if none has run
+func (a *App) LastHealth() (HealthReport, bool) {
+	a.healthMutex.Lock()
+	defer a.healthMutex.Unlock()
+	if a.lastHealth == nil {
+		return HealthReport{}, false
+	}
+	return *a.lastHealth, true
+}
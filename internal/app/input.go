@@ -0,0 +1,66 @@
+package app
+
+import (
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// cursorStyle marks the character under the text input cursor
+var cursorStyle = lipgloss.NewStyle().Reverse(true)
+
+// The text input cursor counts runes rather than bytes, so moving and deleting never split a
+// multi-byte character.
+
+// setInput replaces the text input, leaving the cursor at its end
+func (m *Model) setInput(text string) {
+	m.textInput = text
+	m.inputCursor = utf8.RuneCountInString(text)
+}
+
+// insertInput inserts text at the cursor and moves the cursor past it
+func (m *Model) insertInput(text string) {
+	runes := []rune(m.textInput)
+	at := m.clampedCursor()
+	inserted := []rune(text)
+	m.textInput = string(runes[:at]) + text + string(runes[at:])
+	m.inputCursor = at + len(inserted)
+}
+
+// deleteInput removes the character before the cursor, or the one under it when forward is set
+func (m *Model) deleteInput(forward bool) {
+	runes := []rune(m.textInput)
+	at := m.clampedCursor()
+	if !forward {
+		if at == 0 {
+			return
+		}
+		at--
+	}
+	if at >= len(runes) {
+		return
+	}
+	m.textInput = string(runes[:at]) + string(runes[at+1:])
+	m.inputCursor = at
+}
+
+// moveCursor moves the text input cursor by delta characters, stopping at either end
+func (m *Model) moveCursor(delta int) {
+	m.inputCursor = max(0, min(m.clampedCursor()+delta, utf8.RuneCountInString(m.textInput)))
+}
+
+// clampedCursor returns the cursor position, kept within the text input
+func (m *Model) clampedCursor() int {
+	return max(0, min(m.inputCursor, utf8.RuneCountInString(m.textInput)))
+}
+
+// inputWithCursor returns the text input with the cursor drawn at its position: a block past
+// the end, or the character under it highlighted
+func (m *Model) inputWithCursor() string {
+	runes := []rune(m.textInput)
+	at := m.clampedCursor()
+	if at == len(runes) {
+		return m.textInput + "█"
+	}
+	return string(runes[:at]) + cursorStyle.Render(string(runes[at])) + string(runes[at+1:])
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"bytes"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// evictableFile is a file the janitor may delete to bring disk usage under the cap
+type evictableFile struct {
+	path string
+	info fs.FileInfo
+}
+
+// EnforceDiskUsage deletes the oldest cached audio and replies, then exports, until the files
+// under ConfigDir fit in MaxDiskUsageMB. If that isn't enough the conversation log is trimmed to
+// the entries it shows; settings and other state are never removed.
+func (a *App) EnforceDiskUsage() error {
+	if a.config.MaxDiskUsageMB <= 0 {
+		return nil
+	}
+	limit := int64(a.config.MaxDiskUsageMB) * 1024 * 1024
+
+	usage, err := dirSize(a.config.ConfigDir)
+	if err != nil {
+		return err
+	}
+	if usage <= limit {
+		return nil
+	}
+
+	// Cached audio and replies can always be regenerated, so they go before exports.
+	// Like wipeTempDir, only jork's own files in a temp directory outside the config dir are
+	// touched, and only when that is explicitly allowed.
+	inConfigDir := a.config.IsWithinConfigDir(a.config.AudioTempDir)
+	var candidates []evictableFile
+	for _, dir := range []string{a.config.AudioTempDir, filepath.Join(a.config.ConfigDir, "cache"), filepath.Join(a.config.ConfigDir, "exports")} {
+		if dir == a.config.AudioTempDir && !inConfigDir && !a.config.AllowExternalTempDir {
+			continue
+		}
+		files, err := regularFiles(dir)
+		if err != nil {
+			return err
+		}
+		if dir == a.config.AudioTempDir {
+			files = slices.DeleteFunc(files, func(file evictableFile) bool { return !isTempAudioFile(file.info.Name()) })
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].info.ModTime().Before(files[j].info.ModTime()) })
+		candidates = append(candidates, files...)
+	}
+
+	for _, file := range candidates {
+		if usage <= limit {
+			break
+		}
+		if err := os.Remove(file.path); err != nil {
+			log.Printf("Disk janitor could not remove %s: %v", file.path, err)
+			continue
+		}
+		log.Printf("Disk janitor removed %s (%d bytes) to stay under %d MB", file.path, file.info.Size(), a.config.MaxDiskUsageMB)
+		if inConfigDir || a.config.IsWithinConfigDir(file.path) {
+			usage -= file.info.Size()
+		}
+	}
+	if usage > limit && !a.config.Locked() && a.config.IsWithinConfigDir(a.config.LogFile) {
+		freed, err := a.trimConversationLog()
+		if err != nil {
+			return err
+		}
+		usage -= freed
+	}
+	if usage > limit {
+		log.Printf("Disk usage under %s is still %d bytes, above the %d MB cap, after evicting all cached audio and exports", a.config.ConfigDir, usage, a.config.MaxDiskUsageMB)
+	}
+	return nil
+}
+
+// tempAudioPrefixes start the names of the files jork writes to the audio temp directory
+var tempAudioPrefixes = []string{"jork_", "response_", "input_", "compare_", "sample_", "selftest_", "spoken_error_", "export_", "warmup"}
+
+// isTempAudioFile reports whether name is one of jork's files in the audio temp directory
+func isTempAudioFile(name string) bool {
+	for _, prefix := range tempAudioPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimConversationLog rewrites the log file with only the last MaxConversationHistory entries,
+// the ones LoadConversationLog keeps, and returns how many bytes that freed
+func (a *App) trimConversationLog() (int64, error) {
+	data, err := os.ReadFile(a.config.LogFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if err := a.LoadConversationLog(); err != nil {
+		return 0, err
+	}
+	removed := bytes.Count(data, []byte("\n")) - len(a.state.ConversationLog)
+	if removed <= 0 {
+		return 0, nil
+	}
+	if err := a.SaveConversationLog(); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(a.config.LogFile)
+	if err != nil {
+		return 0, err
+	}
+	freed := int64(len(data)) - info.Size()
+	log.Printf("Disk janitor removed the %d oldest entries of %s (%d bytes) to stay under %d MB", removed, a.config.LogFile, freed, a.config.MaxDiskUsageMB)
+	return freed, nil
+}
+
+// dirSize returns the total size of the regular files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+// regularFiles lists the regular files directly inside dir; a missing dir has none
+func regularFiles(dir string) ([]evictableFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []evictableFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, evictableFile{path: filepath.Join(dir, entry.Name()), info: info})
+	}
+	return files, nil
+}
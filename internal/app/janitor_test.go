@@ -0,0 +1,68 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jorkle/jork/internal/config"
+)
+
+func TestEnforceDiskUsageExternalTempDir(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		configDir, tempDir := t.TempDir(), t.TempDir()
+		cfg := config.DefaultConfig()
+		cfg.ConfigDir = configDir
+		cfg.AudioTempDir = tempDir
+		cfg.AllowExternalTempDir = allow
+		cfg.LogFile = ""
+		cfg.MaxDiskUsageMB = 1
+
+		// Settings and state are never evicted, so usage stays over the cap
+		writeFile(t, filepath.Join(configDir, "state.bin"), 2<<20)
+		userFile := filepath.Join(tempDir, "notes.txt")
+		jorkFile := filepath.Join(tempDir, "response_1700000000.mp3")
+		writeFile(t, userFile, 10)
+		writeFile(t, jorkFile, 10)
+
+		a := &App{config: cfg}
+		if err := a.EnforceDiskUsage(); err != nil {
+			t.Fatalf("allow %v: %v", allow, err)
+		}
+		if !exists(userFile) {
+			t.Errorf("allow %v: a file jork didn't write was removed", allow)
+		}
+		if exists(jorkFile) == allow {
+			t.Errorf("allow %v: jork's temp file exists = %v", allow, exists(jorkFile))
+		}
+	}
+}
+
+func TestIsTempAudioFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"response_1700000000.mp3": true,
+		"jork_stream_123.opus":    true,
+		"input_1700000000.wav":    true,
+		"warmup.mp3":              true,
+		"notes.txt":               false,
+		"my_response.mp3":         false,
+	} {
+		if got := isTempAudioFile(name); got != want {
+			t.Errorf("isTempAudioFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// writeFile creates path holding size bytes
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.WriteFile(path, make([]byte, size), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exists reports whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// markdownTheme styles the parts of a reply rendered from markdown
+type markdownTheme struct {
+	heading lipgloss.Style
+	code    lipgloss.Style
+	quote   lipgloss.Style
+	bold    lipgloss.Style
+	italic  lipgloss.Style
+	rule    lipgloss.Style
+}
+
+// newMarkdownTheme returns the theme for a Config.MarkdownStyle of "auto", "dark" or "light".
+// Auto colors adapt to the terminal background; terminals without color get plain styling.
+func newMarkdownTheme(style string) markdownTheme {
+	color := func(light, dark string) lipgloss.TerminalColor {
+		switch style {
+		case "dark":
+			return lipgloss.Color(dark)
+		case "light":
+			return lipgloss.Color(light)
+		}
+		return lipgloss.AdaptiveColor{Light: light, Dark: dark}
+	}
+	return markdownTheme{
+		heading: lipgloss.NewStyle().Bold(true).Foreground(color("30", "86")),
+		code:    lipgloss.NewStyle().Foreground(color("130", "215")),
+		quote:   lipgloss.NewStyle().Italic(true).Foreground(color("240", "245")),
+		bold:    lipgloss.NewStyle().Bold(true),
+		italic:  lipgloss.NewStyle().Italic(true),
+		rule:    lipgloss.NewStyle().Foreground(color("250", "241")),
+	}
+}
+
+var (
+	headingPattern  = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+	bulletPattern   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	rulePattern     = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	boldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicPattern   = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+	inlineCodeRegex = regexp.MustCompile("`([^`]+)`")
+)
+
+// renderMarkdown renders the markdown replies commonly use (headings, lists, quotes, rules,
+// emphasis, inline code and code fences) for the terminal, wrapped to width like wrapText.
+// It works line by line, so a reply that is still streaming renders as far as it has come.
+func (t markdownTheme) renderMarkdown(text string, width int) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	inCode := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			if width > 0 {
+				line = ansi.Hardwrap(line, width, true)
+			}
+			out = append(out, t.code.Render(line))
+			continue
+		}
+
+		var rendered string
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			rendered = t.heading.Render(m[1])
+		} else if rulePattern.MatchString(line) {
+			n := 40
+			if width > 0 {
+				n = min(n, width)
+			}
+			rendered = t.rule.Render(strings.Repeat("─", n))
+		} else if m := bulletPattern.FindStringSubmatch(line); m != nil {
+			rendered = m[1] + "• " + t.inline(m[2])
+		} else if rest, ok := strings.CutPrefix(line, ">"); ok {
+			rendered = t.quote.Render("│ " + strings.TrimSpace(rest))
+		} else {
+			rendered = t.inline(line)
+		}
+		if width > 0 {
+			rendered = ansi.Wrap(rendered, width, "")
+		}
+		out = append(out, rendered)
+	}
+	return strings.Join(out, "\n")
+}
+
+// inline renders the code spans and emphasis within a line; emphasis inside code is left alone
+func (t markdownTheme) inline(line string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range inlineCodeRegex.FindAllStringSubmatchIndex(line, -1) {
+		b.WriteString(t.emphasis(line[last:span[0]]))
+		b.WriteString(t.code.Render(line[span[2]:span[3]]))
+		last = span[1]
+	}
+	b.WriteString(t.emphasis(line[last:]))
+	return b.String()
+}
+
+// emphasis renders **bold** and *italic* text
+func (t markdownTheme) emphasis(text string) string {
+	text = boldPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := boldPattern.FindStringSubmatch(s)
+		return t.bold.Render(m[1] + m[2])
+	})
+	return italicPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := italicPattern.FindStringSubmatch(s)
+		return t.italic.Render(m[1] + m[2])
+	})
+}
+
+// renderReply wraps a reply from name to width, rendering its markdown unless the mode speaks
+// replies or Config.MarkdownStyle is "plain"
+func (m *Model) renderReply(name, text string, width int) string {
+	style := m.app.config.MarkdownStyle
+	if style == "plain" || m.app.state.CurrentMode.UsesVoiceOutput() {
+		return wrapText(name+": "+text, width)
+	}
+	// The name only lengthens the first line, so rewrapping leaves the rest as rendered
+	rendered := name + ": " + newMarkdownTheme(style).renderMarkdown(text, width)
+	if width > 0 {
+		rendered = ansi.Wrap(rendered, width, "")
+	}
+	return rendered
+}
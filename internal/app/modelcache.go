@@ -0,0 +1,75 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jorkle/jork/internal/ai"
+)
+
+// modelCacheFile holds the fetched model lists in ConfigDir
+const modelCacheFile = "models_cache.json"
+
+// modelCacheEntry is one provider's model list and when it was fetched
+type modelCacheEntry struct {
+	Models    []string  `json:"models"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// modelCachePath returns the location of the model list cache
+func (a *App) modelCachePath() string {
+	return filepath.Join(a.config.ConfigDir, modelCacheFile)
+}
+
+// readModelCache loads the cached model lists keyed by provider; a missing or unreadable cache is empty
+func (a *App) readModelCache() map[ai.Provider]modelCacheEntry {
+	cache := make(map[ai.Provider]modelCacheEntry)
+	data, err := os.ReadFile(a.modelCachePath())
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return make(map[ai.Provider]modelCacheEntry)
+	}
+	return cache
+}
+
+// CachedModels returns the cached models of the current provider and whether they are
+// missing or older than the configured TTL and should be refreshed
+func (a *App) CachedModels() ([]string, bool) {
+	entry, ok := a.readModelCache()[a.openaiClient.Provider()]
+	if !ok {
+		return nil, true
+	}
+	ttl := time.Duration(a.config.ModelCacheTTLHours) * time.Hour
+	return entry.Models, time.Since(entry.FetchedAt) > ttl
+}
+
+// RefreshModels fetches the current provider's models and stores them in the cache
+func (a *App) RefreshModels() ([]string, error) {
+	models, err := a.openaiClient.FetchAvailableModels()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.storeModels(models); err != nil {
+		return models, fmt.Errorf("failed to cache models: %w", err)
+	}
+	return models, nil
+}
+
+// storeModels saves models as the current provider's cached list
+func (a *App) storeModels(models []string) error {
+	cache := a.readModelCache()
+	cache[a.openaiClient.Provider()] = modelCacheEntry{Models: models, FetchedAt: time.Now()}
+	data, err := json.MarshalIndent(cache, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(a.config.ConfigDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(a.modelCachePath(), data, 0644)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jorkle/jork/internal/audio"
+	"github.com/jorkle/jork/internal/models"
+	"github.com/jorkle/jork/pkg/engine"
+)
+
+// selfTestRecordDuration is how long the self-test records from the microphone
+const selfTestRecordDuration = 3 * time.Second
+
+// outputTestDuration is how long the output test tone plays
+const outputTestDuration = time.Second
+
+// TestOutput plays a short tone through the WAV playback path and returns the backend that played it
+func (a *App) TestOutput() (string, error) {
+	backend := a.player.WAVBackendName()
+	if backend == "" {
+		return "", fmt.Errorf("no WAV player is available")
+	}
+	_ = a.StopAudio()
+	tone := audio.GenerateTone(a.config.TestToneFrequency, outputTestDuration, a.config.SampleRate)
+	if err := a.player.PlayAudioData(tone); err != nil {
+		return "", fmt.Errorf("failed to play test tone via %s: %w", backend, err)
+	}
+	a.player.WaitForPlayback()
+	return backend, nil
+}
+
+// selfTestStage is one step of the self-test pipeline
+type selfTestStage struct {
+	name string
+	run  func() (string, error)
+}
+
+// SelfTest exercises the full voice pipeline outside the TUI, writing a pass/fail line with timing for each stage.
+// Stages after a failure are skipped since they depend on its output. It returns an error if any stage failed.
+func (a *App) SelfTest(out io.Writer) error {
+	var audioData *models.AudioData
+	var transcription, response string
+	wavFile, err := a.config.AudioTempPath("selftest_input.wav")
+	if err != nil {
+		return err
+	}
+	responseFile := filepath.Join(filepath.Dir(wavFile), "selftest_response"+a.ttsClient.Extension())
+	defer os.Remove(wavFile)
+	defer os.Remove(responseFile)
+
+	stages := []selfTestStage{
+		{"Validate API keys", func() (string, error) {
+			return "", a.engine.ValidateForMode(models.VoiceToVoice)
+		}},
+		{fmt.Sprintf("Record %s of audio", selfTestRecordDuration), func() (string, error) {
+			fmt.Fprintln(out, "       Speak now...")
+			if err := a.recorder.StartRecording(); err != nil {
+				return "", err
+			}
+			time.Sleep(selfTestRecordDuration)
+			data, err := a.recorder.StopRecording()
+			if err != nil {
+				return "", err
+			}
+			audioData = data
+			return fmt.Sprintf("%d samples", len(data.Data)), nil
+		}},
+		{"Transcribe recording", func() (string, error) {
+			if err := a.recorder.SaveToWAV(audio.DownmixToMono(audioData), wavFile); err != nil {
+				return "", err
+			}
+			text, err := a.sttClient.SpeechToText(wavFile)
+			if err != nil {
+				return "", err
+			}
+			transcription = strings.TrimSpace(text)
+			if transcription == "" {
+				// A muted or dead microphone records silence, so this is the check that catches it
+				return "", engine.ErrEmptyTranscription
+			}
+			return fmt.Sprintf("%q", transcription), nil
+		}},
+		{"Generate response", func() (string, error) {
+			text, err := a.openaiClient.GenerateResponse(transcription, a.state.KnowledgeLevel, models.TextToVoice, nil, a.engine.Topic())
+			if err != nil {
+				return "", err
+			}
+			response = text
+			return fmt.Sprintf("%d characters", len(response)), nil
+		}},
+		{"Synthesize speech", func() (string, error) {
+			return "", a.ttsClient.TextToSpeech(response, responseFile)
+		}},
+		{"Play test tone", func() (string, error) {
+			backend, err := a.TestOutput()
+			if err != nil {
+				return "", err
+			}
+			return "via " + backend, nil
+		}},
+		{"Play response", func() (string, error) {
+			if err := a.PlayAudio(responseFile); err != nil {
+				return "", err
+			}
+			a.player.WaitForPlayback()
+			return "", nil
+		}},
+	}
+
+	failed := false
+	for _, stage := range stages {
+		if failed {
+			fmt.Fprintf(out, "[SKIP] %s\n", stage.name)
+			continue
+		}
+		start := time.Now()
+		detail, err := stage.run()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			failed = true
+			fmt.Fprintf(out, "[FAIL] %s (%s): %v\n", stage.name, elapsed, err)
+			continue
+		}
+		if detail != "" {
+			fmt.Fprintf(out, "[PASS] %s (%s): %s\n", stage.name, elapsed, detail)
+		} else {
+			fmt.Fprintf(out, "[PASS] %s (%s)\n", stage.name, elapsed)
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("self-test failed")
+	}
+	return nil
+}
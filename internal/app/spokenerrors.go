@@ -0,0 +1,77 @@
+package app
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"github.com/jorkle/jork/internal/ai"
+	"github.com/jorkle/jork/internal/audio"
+	"github.com/jorkle/jork/internal/models"
+)
+
+// spokenErrorPhrase is a short fixed sentence announcing a kind of error
+type spokenErrorPhrase struct {
+	key  string // names the cached audio file
+	text string
+}
+
+// spokenErrorFor picks the phrase announcing err. The phrases are fixed so announcing
+// an error never depends on the chat provider that may have caused it.
+func spokenErrorFor(err error) spokenErrorPhrase {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ai.ErrNoAPIKey):
+		return spokenErrorPhrase{"no_key", "Sorry, no API key is set up. Please check the screen."}
+	case errors.Is(err, ai.ErrContentRefused):
+		return spokenErrorPhrase{"refused", "Sorry, I can't answer that one."}
+	case errors.Is(err, ai.ErrContextLengthExceeded):
+		return spokenErrorPhrase{"too_long", "Sorry, the conversation got too long. Try clearing it."}
+	case errors.As(err, &netErr):
+		return spokenErrorPhrase{"unreachable", "Sorry, I couldn't reach the server."}
+	default:
+		return spokenErrorPhrase{"generic", "Sorry, something went wrong. Please check the screen."}
+	}
+}
+
+// errorToneFrequency and errorToneDuration describe the tone played when an error can't be spoken
+const (
+	errorToneFrequency = 220
+	errorToneDuration  = 400 * time.Millisecond
+)
+
+// AnnounceError speaks a short phrase for err when SpeakErrors is set and the session is
+// VoiceToVoice, where on-screen errors go unnoticed. If speech can't be synthesized,
+// a low tone is played instead so the failure is still audible.
+func (a *App) AnnounceError(err error) {
+	if err == nil || !a.config.SpeakErrors || a.state.CurrentMode != models.VoiceToVoice {
+		return
+	}
+	if a.player.IsPlaying() {
+		return
+	}
+
+	phrase := spokenErrorFor(err)
+	if filename, synthErr := a.spokenErrorFile(phrase); synthErr == nil {
+		if playErr := a.PlayAudio(filename); playErr == nil {
+			return
+		}
+	}
+	_ = a.player.PlayAudioData(audio.GenerateTone(errorToneFrequency, errorToneDuration, a.config.SampleRate))
+}
+
+// spokenErrorFile returns the synthesized audio for phrase, reusing an earlier synthesis
+func (a *App) spokenErrorFile(phrase spokenErrorPhrase) (string, error) {
+	filename, err := a.config.AudioTempPath("spoken_error_" + phrase.key + a.ttsClient.Extension())
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return filename, nil
+	}
+	if err := a.ttsClient.TextToSpeech(phrase.text, filename); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
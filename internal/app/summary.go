@@ -0,0 +1,48 @@
+package app
+
+// SessionSummary returns the last generated session summary and whether turns were
+// added since, so that it is out of date
+func (a *App) SessionSummary() (string, bool) {
+	a.summaryMutex.Lock()
+	defer a.summaryMutex.Unlock()
+	return a.summary, len(a.engine.History()) != a.summaryTurns
+}
+
+// SessionSummaryDue reports whether summaries are enabled and enough turns were added
+// since the last summary to regenerate it
+func (a *App) SessionSummaryDue() bool {
+	if !a.config.SessionSummaries || a.config.SessionSummaryEvery <= 0 {
+		return false
+	}
+	a.summaryMutex.Lock()
+	defer a.summaryMutex.Unlock()
+	return len(a.engine.History())-a.summaryTurns >= a.config.SessionSummaryEvery
+}
+
+// UpdateSessionSummary summarizes the conversation so far. The cached summary is returned
+// without a request when no turns were added since it was generated.
+func (a *App) UpdateSessionSummary() (string, error) {
+	history := a.engine.History()
+
+	a.summaryMutex.Lock()
+	if len(history) == 0 {
+		// The history was cleared, so nothing is covered any more
+		a.summary, a.summaryTurns = "", 0
+	}
+	if len(history) == a.summaryTurns {
+		summary := a.summary
+		a.summaryMutex.Unlock()
+		return summary, nil
+	}
+	a.summaryMutex.Unlock()
+	summary, err := a.openaiClient.SummarizeSession(history)
+	if err != nil {
+		return "", err
+	}
+
+	a.summaryMutex.Lock()
+	defer a.summaryMutex.Unlock()
+	a.summary = summary
+	a.summaryTurns = len(history)
+	return summary, nil
+}
@@ -1,14 +1,20 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jorkle/jork/internal/ai"
+	"github.com/jorkle/jork/internal/audio"
 	"github.com/jorkle/jork/internal/models"
+	"github.com/jorkle/jork/pkg/engine"
 )
 
 // UIState represents the current UI state
@@ -25,6 +31,18 @@ const (
 	SettingsEdit // NEW: Settings edit dialog state
 	APIKeyInput  // NEW: API Key input dialog state
 	APIKeyVerifying // NEW: API Key verifying state
+	ComparisonSetup // Picking the two knowledge levels to compare
+	Comparison      // Side-by-side knowledge level comparison
+	History         // Conversation history view
+	TagInput        // Editing the tags applied to new turns
+	ConfirmQuit     // Asking before a quit that would lose a conversation, recording or playback
+	StartupWizard   // First-run setup
+	ConfirmTopicChange // Offering a fresh context for an input on a new topic
+	SessionSummary     // What the session has covered so far
+	Passphrase         // Entering the passphrase of encrypted settings
+	TopicInput         // Editing the default conversation topic from Settings
+	Health             // Per-service health check results
+	ReviewRecording    // Playing back, sending or discarding a finished recording
 )
 
 // Model represents the Bubbletea model
@@ -32,6 +50,7 @@ type Model struct {
 	app             *App
 	uiState         UIState
 	textInput       string
+	inputCursor     int // position of the text input cursor, in characters
 	cursor          int
 	selectedMode    int
 	selectedLevel   int
@@ -39,8 +58,14 @@ type Model struct {
 	message         string
 	error           string
 	lastResponse    string
-	recording       bool
+	recording       bool // the recorder is running and no stop has been issued for it yet
+	discardRecording bool // the recording was cancelled while stopping, so its audio is dropped
 	recordingTime   time.Duration
+	inputLevel      float32 // microphone amplitude at the last recording tick
+	recordingStart  time.Time
+	pausedAt        time.Time // when the current recording was paused, zero while capturing
+	reviewAudio     *models.AudioData // recording waiting in ReviewRecording to be sent or discarded
+	recordingID     int // incremented per recording so ticks from earlier sessions stop
 	width           int
 	height          int
 	isSamplingVoice bool // NEW: flag for TTS voice sample playback
@@ -48,13 +73,84 @@ type Model struct {
 	editOptions     []string
 	openaiKeyInput  string // NEW: for OpenAI API key input
 	openaiKeyError  string // NEW: for displaying API key error
+	compareLevels   [2]int
+	compareColumn   int
+	compareFirst    string
+	compareSecond   string
+	inputHistory    []string // inputs submitted this session, oldest first
+	historyIndex    int      // position in inputHistory while recalling, len(inputHistory) otherwise
+	recalledInput   string   // history entry the current input was recalled from, if any
+	draftInput      string   // input typed before recalling history, restored past the newest entry
+	status          string   // brief notice shown in the conversation view
+	requestID       int      // ID of the most recent processing request
+	cancelledID     int      // ID of the last request the user cancelled
+	tagInput        string   // comma separated tags being edited
+	topicInput      string   // default conversation topic being edited
+	historyView     viewport // scroll position of the history view
+	historyToCursor bool     // scroll the history view to the selected entry on the next render
+	convView        viewport // scroll position of the response in the conversation view
+	historyFilter   string   // tag the history view is filtered by, empty for all
+	historyCursor   int      // index in the conversation log of the entry selected in the history view
+	quitReason      string   // what would be lost by quitting, shown in ConfirmQuit
+	quitReturnState UIState  // state to return to when a quit is declined
+	exporting       bool     // a conversation audio export is running
+	exportStatus    string   // progress or result of the last audio export
+	retrySpeech     string   // text whose synthesis failed and can be retried, empty otherwise
+	streaming       bool     // response chunks are still arriving
+	cancelRequest   context.CancelFunc // aborts the processing request in flight
+	streamID        int      // incremented per stream so caret ticks from earlier streams stop
+	caretVisible    bool     // blink phase of the typing caret
+	windowTitle     string   // terminal title last emitted
+	pendingInput    string   // input held while asking whether to start a fresh context
+	pendingEdit     time.Time // entry the pending input was edited from, if any
+	settingsStatus  string    // progress or result of the last Settings action
+	summarizing     bool      // a session summary is being generated
+	summaryError    string    // why the last session summary failed, if it did
+	healthChecking  bool      // a health check is running
+	reportHealth    bool      // show the running health check's outcome in Settings
+	pendingAudio    string    // synthesized response waiting for its autoplay delay or the user
+	autoplayID      int       // incremented per pending response so earlier autoplay timers are ignored
+	passphraseInput string    // passphrase being typed, never rendered in clear
+	passphraseError string    // why the last passphrase was rejected
+	passphraseNew   bool      // choosing a passphrase to encrypt with rather than unlocking
+	passphraseBack  UIState   // state to return to once a new passphrase is set
+	copyStatus      string    // result of the last clipboard copy, cleared after copyStatusDuration
+	copyID          int       // incremented per copy so earlier expiry timers are ignored
+	skipCache       bool      // the input being sent was prefixed with /nocache
 }
 
+// autoplayDueMsg fires when the autoplay delay of a pending response has passed
+type autoplayDueMsg struct {
+	id int
+}
+
+// copyStatusExpiredMsg fires when the result of a clipboard copy has been shown long enough
+type copyStatusExpiredMsg struct {
+	id int
+}
+
+// copyStatusDuration is how long the result of a clipboard copy stays on screen
+const copyStatusDuration = 2 * time.Second
+
 // NewModel creates a new Bubbletea model
 func NewModel(app *App) *Model {
+	uiState := MainMenu
+	if app.config.NeedsSetup() {
+		uiState = StartupWizard
+	}
+	passphraseNew := false
+	if app.config.Locked() {
+		uiState = Passphrase
+	} else if app.config.NeedsPassphrase() {
+		// Encryption was turned on for a file that was written unencrypted
+		uiState = Passphrase
+		passphraseNew = true
+	}
 	return &Model{
 		app:           app,
-		uiState:       MainMenu,
+		uiState:       uiState,
+		passphraseNew: passphraseNew,
+		passphraseBack: MainMenu,
 		textInput:     "",
 		cursor:        0,
 		selectedMode:  int(app.state.CurrentMode),
@@ -69,24 +165,131 @@ func (m *Model) Init() tea.Cmd {
 	return nil
 }
 
-// Update handles messages and updates the model
+// Update handles messages and updates the model, retitling the terminal when the session changes
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	confirmingQuit := m.uiState == ConfirmQuit
+	model, cmd := m.update(msg)
+	if _, isKey := msg.(tea.KeyMsg); confirmingQuit && !isKey && m.uiState != ConfirmQuit {
+		// A turn finished behind the quit prompt; keep asking, and return to where it left off
+		m.quitReturnState = m.uiState
+		m.uiState = ConfirmQuit
+	}
+	if !m.app.config.TerminalTitle {
+		return model, cmd
+	}
+	if title := m.terminalTitle(); title != m.windowTitle {
+		m.windowTitle = title
+		cmd = tea.Batch(cmd, tea.SetWindowTitle(title))
+	}
+	return model, cmd
+}
+
+// terminalTitle describes the session for the terminal's title bar
+func (m *Model) terminalTitle() string {
+	state := m.app.GetState()
+	title := fmt.Sprintf("jork: %s/%s", state.KnowledgeLevel, state.CurrentMode)
+	if state.Title != "" {
+		title += " — " + state.Title
+	}
+	return title
+}
+
+// update applies msg to the model
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.historyView.setHeight(max(m.height-historyChrome, minViewportHeight))
+		m.convView.setHeight(max(m.height-conversationChrome, minViewportHeight))
+		return m, nil
+
+	case tea.MouseMsg:
+		if msg.Action != tea.MouseActionPress {
+			return m, nil
+		}
+		step := 0
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			step = -wheelLines
+		case tea.MouseButtonWheelDown:
+			step = wheelLines
+		}
+		switch m.uiState {
+		case History:
+			m.historyView.scrollBy(step)
+		case Conversation:
+			m.convView.scrollBy(step)
+		}
 		return m, nil
 
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
 
-	case recordingTickMsg:
-		if m.recording {
-			m.recordingTime = msg.duration
-			return m, m.tickRecording()
+	case ExportProgressMsg:
+		m.exportStatus = fmt.Sprintf("Exporting audio: %d/%d clips synthesized", msg.Done, msg.Total)
+		return m, waitForExportCmd(msg.updates)
+
+	case ClipboardCopiedMsg:
+		m.copyStatus = "Copied to clipboard"
+		if msg.Error != nil {
+			m.copyStatus = "Could not copy: " + msg.Error.Error()
+		}
+		m.copyID++
+		id := m.copyID
+		return m, tea.Tick(copyStatusDuration, func(time.Time) tea.Msg { return copyStatusExpiredMsg{id: id} })
+
+	case copyStatusExpiredMsg:
+		if msg.id == m.copyID {
+			m.copyStatus = ""
+		}
+		return m, nil
+
+	case ExportCompletedMsg:
+		m.exporting = false
+		if msg.Error != nil {
+			m.exportStatus = "Export failed: " + msg.Error.Error()
+		} else {
+			m.exportStatus = "Exported to " + msg.Path
 		}
 		return m, nil
 
+	case responseChunkMsg:
+		next := waitForStreamCmd(msg.updates)
+		if msg.requestID == m.cancelledID {
+			// Drain the abandoned stream until it winds down
+			return m, next
+		}
+		if !m.streaming {
+			// First chunk: show the reply growing in the conversation view
+			m.uiState = Conversation
+			m.lastResponse = ""
+			m.convView.gotoBottom()
+			next = tea.Batch(next, m.startStreamIndicator())
+		}
+		m.lastResponse += msg.text
+		return m, next
+
+	case caretTickMsg:
+		if !m.streaming || msg.stream != m.streamID {
+			return m, nil
+		}
+		m.caretVisible = !m.caretVisible
+		return m, m.tickCaret()
+
+	case recordingTickMsg:
+		if !m.recording || m.uiState != Recording || msg.session != m.recordingID {
+			return m, nil
+		}
+		m.recordingTime = m.recordedTime(msg.at)
+		m.inputLevel = m.app.InputLevel()
+		if limit := m.maxRecordingTime(); limit > 0 && m.recordingTime >= limit {
+			model, cmd := m.stopRecording()
+			m.status = fmt.Sprintf("Stopped at the %.0fs recording limit", limit.Seconds())
+			return model, cmd
+		}
+		return m, m.tickRecording()
+
 	case processingDoneMsg:
 		m.uiState = Conversation
 		m.lastResponse = msg.response
@@ -95,22 +298,107 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case RecordingStartedMsg:
 		m.recording = true
+		m.discardRecording = false
 		m.recordingTime = 0
+		m.inputLevel = 0
+		m.recordingStart = time.Now()
+		m.pausedAt = time.Time{}
+		m.recordingID++
 		m.uiState = Recording
-		return m, m.tickRecording()
+		if msg.Warning != "" {
+			m.status = msg.Warning
+		}
+		return m, tea.Batch(m.tickRecording(), WaitForSilenceCmd(m.app.AutoStop(), m.recordingID))
+
+	case silenceDetectedMsg:
+		if !m.recording || m.uiState != Recording || msg.session != m.recordingID {
+			return m, nil
+		}
+		return m.stopRecording()
 
 	case RecordingStoppedMsg:
-		m.recording = false
+		if m.discardRecording {
+			m.discardRecording = false
+			m.uiState = Conversation
+			return m, nil
+		}
 		if msg.Error != nil {
 			m.error = msg.Error.Error()
 			m.uiState = Conversation
-		} else {
-			m.uiState = Processing
-			return m, m.processVoiceInput(msg.AudioData.(*models.AudioData))
+			return m, AnnounceErrorCmd(m.app, msg.Error)
+		}
+		m.error = ""
+		if m.app.config.ReviewBeforeSend {
+			m.reviewAudio = msg.AudioData.(*models.AudioData)
+			m.uiState = ReviewRecording
+			return m, nil
+		}
+		m.uiState = Processing
+		return m, m.processVoiceInput(msg.AudioData.(*models.AudioData))
+
+	case processingCancelledMsg:
+		m.cancelledID = msg.requestID
+		if m.cancelRequest != nil {
+			m.cancelRequest()
+			m.cancelRequest = nil
+		}
+		if m.streaming {
+			m.stopStreamIndicator()
+			m.lastResponse = ""
 		}
+		m.uiState = Conversation
+		m.status = cancelledStatus
 		return m, nil
 
 	case ProcessingCompletedMsg:
+		if msg.RequestID != 0 && msg.RequestID == m.cancelledID {
+			// Late result of an abandoned turn; drop it and its log entry
+			if !msg.Timestamp.IsZero() {
+				m.app.engine.DiscardEntry(msg.Timestamp)
+			}
+			return m, nil
+		}
+		if m.cancelRequest != nil {
+			m.cancelRequest()
+			m.cancelRequest = nil
+		}
+		if !m.streaming {
+			// A streamed reply is already in view wherever the user scrolled it
+			m.convView.gotoBottom()
+		}
+		m.stopStreamIndicator()
+		if errors.Is(msg.Error, engine.ErrCancelled) {
+			// Not a failure, so it's reported as a notice rather than an error
+			m.uiState = Conversation
+			m.lastResponse = ""
+			m.error = ""
+			m.status = cancelledStatus
+			return m, nil
+		}
+		m.status = ""
+		if msg.Trimmed {
+			m.status = "Older context was trimmed to fit the model's limit"
+		}
+		if errors.Is(msg.Error, ai.ErrNoAPIKey) {
+			// Requests can't succeed until a key is entered, so go straight to the key prompt
+			m.openaiKeyError = "No API key configured"
+			m.openaiKeyInput = ""
+			m.uiState = APIKeyInput
+			return m, AnnounceErrorCmd(m.app, msg.Error)
+		}
+		if errors.Is(msg.Error, ai.ErrContentRefused) {
+			// A policy outcome rather than a failure; nothing was logged for the turn
+			m.uiState = Conversation
+			m.lastResponse = ""
+			m.error = ""
+			m.status = "The model declined to answer this"
+			return m, AnnounceErrorCmd(m.app, msg.Error)
+		}
+		if errors.Is(msg.Error, engine.ErrEmptyTranscription) {
+			// Nothing was heard, so go straight back to recording instead of sending an empty turn
+			m.error = msg.Error.Error()
+			return m, StartRecordingCmd(m.app)
+		}
 		m.uiState = Conversation
 		m.lastResponse = msg.Response
 		if msg.Error != nil {
@@ -118,6 +406,108 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.error = ""
 		}
+		m.retrySpeech = ""
+		if msg.SpeechErr != nil {
+			m.error = "Could not speak the response: " + msg.SpeechErr.Error()
+			if errors.Is(msg.SpeechErr, ai.ErrCorruptAudio) {
+				m.retrySpeech = msg.Speech
+				m.status = "Press Ctrl+P to retry speech synthesis"
+			}
+		}
+		var cmds []tea.Cmd
+		if msg.Error != nil {
+			cmds = append(cmds, AnnounceErrorCmd(m.app, msg.Error))
+		} else if msg.SpeechErr != nil {
+			cmds = append(cmds, AnnounceErrorCmd(m.app, msg.SpeechErr))
+		}
+		if msg.AudioFile != "" {
+			cmds = append(cmds, m.deferPlayback(msg.AudioFile))
+		}
+		if msg.Error == nil && m.app.SessionSummaryDue() {
+			cmds = append(cmds, m.refreshSessionSummary())
+		}
+		return m, tea.Batch(cmds...)
+	case autoplayDueMsg:
+		if msg.id == m.autoplayID && m.pendingAudio != "" {
+			m.playPendingAudio()
+		}
+		return m, nil
+	case SpeechRetriedMsg:
+		m.status = ""
+		if msg.Error != nil {
+			m.error = "Could not speak the response: " + msg.Error.Error()
+			if errors.Is(msg.Error, ai.ErrCorruptAudio) {
+				m.status = "Press Ctrl+P to retry speech synthesis"
+			} else {
+				m.retrySpeech = ""
+			}
+		} else {
+			m.error = ""
+			m.retrySpeech = ""
+		}
+		return m, nil
+	case HealthCheckedMsg:
+		m.healthChecking = false
+		if len(msg.Models) > 0 {
+			m.app.config.AvailableModels = msg.Models
+		}
+		if m.reportHealth {
+			m.reportHealth = false
+			if err := msg.Report.Err(); err != nil {
+				m.settingsStatus = "Health check failed: " + err.Error()
+			} else {
+				m.settingsStatus = "Health check passed"
+			}
+		}
+		return m, nil
+	case SessionSummaryMsg:
+		m.summarizing = false
+		m.summaryError = ""
+		if msg.Error != nil {
+			m.summaryError = msg.Error.Error()
+		}
+		return m, nil
+	case ModelsRefreshedMsg:
+		if len(msg.Models) > 0 {
+			m.app.config.AvailableModels = msg.Models
+			if m.uiState == SettingsEdit && m.selectedSetting == 0 {
+				m.setModelOptions()
+			}
+		}
+		if msg.Manual {
+			if msg.Error != nil {
+				m.settingsStatus = "Could not refresh models: " + msg.Error.Error()
+			} else {
+				m.settingsStatus = fmt.Sprintf("Refreshed %d models", len(msg.Models))
+			}
+		}
+		return m, nil
+	case OutputTestedMsg:
+		if msg.Error != nil {
+			m.settingsStatus = "Output test failed: " + msg.Error.Error()
+		} else {
+			m.settingsStatus = "Test tone played via " + msg.Backend
+		}
+		return m, nil
+	case ModeValidatedMsg:
+		if msg.Error != nil {
+			m.error = fmt.Sprintf("%s is not available: %s", msg.Mode.String(), msg.Error.Error())
+		}
+		return m, nil
+
+	case ComparisonCompletedMsg:
+		if msg.RequestID != 0 && msg.RequestID == m.cancelledID {
+			return m, nil
+		}
+		if msg.Error != nil {
+			m.error = msg.Error.Error()
+			m.uiState = Conversation
+			return m, nil
+		}
+		m.error = ""
+		m.compareFirst = msg.First
+		m.compareSecond = msg.Second
+		m.uiState = Comparison
 		return m, nil
 	case APIKeyValidationDoneMsg:
 		if msg.err != nil {
@@ -156,6 +546,30 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleAPIKeyInputKeys(msg)
 	case APIKeyVerifying:
 		return m.handleAPIKeyVerifyingKeys(msg)
+	case ComparisonSetup:
+		return m.handleComparisonSetupKeys(msg)
+	case Comparison:
+		return m.handleComparisonKeys(msg)
+	case History:
+		return m.handleHistoryKeys(msg)
+	case TagInput:
+		return m.handleTagInputKeys(msg)
+	case TopicInput:
+		return m.handleTopicInputKeys(msg)
+	case ConfirmQuit:
+		return m.handleConfirmQuitKeys(msg)
+	case StartupWizard:
+		return m.handleStartupWizardKeys(msg)
+	case ConfirmTopicChange:
+		return m.handleConfirmTopicChangeKeys(msg)
+	case SessionSummary:
+		return m.handleSessionSummaryKeys(msg)
+	case Health:
+		return m.handleHealthKeys(msg)
+	case ReviewRecording:
+		return m.handleReviewRecordingKeys(msg)
+	case Passphrase:
+		return m.handlePassphraseKeys(msg)
 	default:
 		return m, nil
 	}
@@ -165,7 +579,7 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m *Model) handleMainMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "q", "ctrl+c":
-		return m, tea.Quit
+		return m.quit()
 	case "1":
 		m.uiState = ModeSelection
 		return m, nil
@@ -177,15 +591,125 @@ func (m *Model) handleMainMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "4":
 		// Show conversation history
-		m.message = m.formatConversationHistory()
+		m.historyFilter = ""
+		m.selectLastHistoryEntry()
+		m.uiState = History
 		return m, nil
 	case "5":
 		m.uiState = Settings
 		return m, nil
+	case "6":
+		m.uiState = SessionSummary
+		return m, m.refreshSessionSummary()
+	case "7":
+		m.uiState = Health
+		if _, ok := m.app.LastHealth(); !ok {
+			return m, m.runHealthCheck()
+		}
+		return m, nil
+	}
+	return m, nil
+}
+
+// runHealthCheck starts a health check unless one is already running
+func (m *Model) runHealthCheck() tea.Cmd {
+	if m.healthChecking {
+		return nil
+	}
+	m.healthChecking = true
+	return HealthCheckCmd(m.app)
+}
+
+// handleHealthKeys handles the health check screen
+func (m *Model) handleHealthKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.uiState = MainMenu
+	case "r":
+		return m, m.runHealthCheck()
+	}
+	return m, nil
+}
+
+// renderHealth renders a checklist of the services and why any failed
+func (m *Model) renderHealth() string {
+	title := titleStyle.Render("Service Health")
+	report, ok := m.app.LastHealth()
+
+	parts := []string{title, ""}
+	switch {
+	case m.healthChecking:
+		parts = append(parts, processingStyle.Render("Checking services..."), "")
+	case !ok:
+		parts = append(parts, statusStyle.Render("No health check has run yet."))
+	}
+	if ok {
+		for _, s := range report.Services {
+			if s.Error == nil {
+				parts = append(parts, selectedStyle.Render("✓ "+s.Name))
+				continue
+			}
+			parts = append(parts, errorStyle.Render("✗ "+s.Name), descriptionStyle.Render("    "+s.Error.Error()))
+		}
+		parts = append(parts, "", statusStyle.Render("Checked at "+report.CheckedAt.Format("15:04:05")))
+	}
+	parts = append(parts, helpStyle.Render("Press 'r' to check again, Esc to go back"))
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// refreshSessionSummary starts regenerating the session summary unless it is current or already being generated
+func (m *Model) refreshSessionSummary() tea.Cmd {
+	if !m.app.config.SessionSummaries {
+		return nil
+	}
+	if _, stale := m.app.SessionSummary(); !stale || m.summarizing {
+		return nil
+	}
+	m.summarizing = true
+	return UpdateSessionSummaryCmd(m.app)
+}
+
+// handleSessionSummaryKeys handles the session summary screen
+func (m *Model) handleSessionSummaryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.uiState = MainMenu
+	case "r":
+		return m, m.refreshSessionSummary()
 	}
 	return m, nil
 }
 
+// renderSessionSummary renders what the session has covered so far
+func (m *Model) renderSessionSummary() string {
+	title := titleStyle.Render("What We've Covered")
+	summary, stale := m.app.SessionSummary()
+
+	var body string
+	switch {
+	case !m.app.config.SessionSummaries:
+		body = statusStyle.Render("Session summaries are turned off (SessionSummaries in the config).")
+	case m.summarizing:
+		body = processingStyle.Render("Summarizing the session...")
+	case summary == "" && len(m.app.engine.History()) == 0:
+		body = statusStyle.Render("Nothing has been covered yet.")
+	case summary == "":
+		body = statusStyle.Render("No summary yet.")
+	default:
+		body = responseStyle.Render(summary)
+	}
+
+	parts := []string{title, "", body, ""}
+	if m.summaryError != "" {
+		parts = append(parts, errorStyle.Render("Error: "+m.summaryError), "")
+	}
+	if stale && !m.summarizing && summary != "" {
+		parts = append(parts, statusStyle.Render("Newer turns aren't included yet"))
+	}
+	parts = append(parts, helpStyle.Render("Press 'r' to update, Esc to go back"))
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
 // handleModeSelectionKeys handles mode selection
 func (m *Model) handleModeSelectionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -203,9 +727,12 @@ func (m *Model) handleModeSelectionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 	case "enter":
-		m.app.SetMode(models.CommunicationMode(m.selectedMode))
+		mode := models.CommunicationMode(m.selectedMode)
+		m.app.SetMode(mode)
+		m.selectedLevel = int(m.app.GetState().KnowledgeLevel)
 		m.uiState = MainMenu
-		return m, nil
+		m.error = ""
+		return m, ValidateModeCmd(m.app, mode)
 	}
 	return m, nil
 }
@@ -236,29 +763,138 @@ func (m *Model) handleKnowledgeLevelKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleConversationKeys handles conversation input
 func (m *Model) handleConversationKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.pendingAudio != "" {
+		switch {
+		case msg.String() == " " && m.textInput == "":
+			m.playPendingAudio()
+			return m, nil
+		case msg.String() == "esc":
+			m.pendingAudio = ""
+			m.status = ""
+			return m, nil
+		}
+	}
+	if m.streaming && msg.String() == "esc" {
+		id := m.requestID
+		return m, func() tea.Msg { return processingCancelledMsg{requestID: id} }
+	}
+	switch msg.String() {
+	case "enter", "ctrl+r", "ctrl+l", "ctrl+t":
+		// Each starts another turn (Enter also /regenerate) or leaves the conversation
+		// view, which the streamed reply's completion would pull the UI back from
+		if m.streaming {
+			m.status = "Wait for the reply to finish, or press Esc to stop it"
+			return m, nil
+		}
+	}
 	switch msg.String() {
 	case "q", "esc":
 		m.uiState = MainMenu
 		return m, nil
 	case "ctrl+c":
-		return m, tea.Quit
+		return m.quit()
 	case "enter":
 		return m.handleConversationSubmit()
 	case "ctrl+r":
 		return m.handleVoiceInput()
-	case "backspace":
-		if len(m.textInput) > 0 {
-			m.textInput = m.textInput[:len(m.textInput)-1]
+	case "ctrl+l":
+		return m.startComparison()
+	case "ctrl+s":
+		m.app.engine.SetStateless(!m.app.engine.Stateless())
+		return m, nil
+	case "ctrl+p":
+		if m.retrySpeech == "" {
+			return m, nil
+		}
+		m.status = "Retrying speech synthesis..."
+		return m, RetrySpeechCmd(m.app, m.retrySpeech)
+	case "pgup":
+		m.convView.pageUp()
+		return m, nil
+	case "pgdown":
+		m.convView.pageDown()
+		return m, nil
+	case "ctrl+y":
+		if m.lastResponse == "" || m.streaming {
+			return m, nil
+		}
+		return m, CopyToClipboardCmd(m.lastResponse)
+	case "ctrl+t":
+		m.tagInput = strings.Join(m.app.engine.Tags(), ", ")
+		m.uiState = TagInput
+		return m, nil
+	case "up":
+		if m.canRecallInput() && m.historyIndex > 0 {
+			if m.historyIndex == len(m.inputHistory) {
+				m.draftInput = m.textInput
+			}
+			m.historyIndex--
+			m.setInput(m.inputHistory[m.historyIndex])
+			m.recalledInput = m.textInput
+		}
+		return m, nil
+	case "down":
+		if m.canRecallInput() && m.historyIndex < len(m.inputHistory) {
+			m.historyIndex++
+			if m.historyIndex == len(m.inputHistory) {
+				m.setInput(m.draftInput)
+				m.draftInput = ""
+				m.recalledInput = ""
+			} else {
+				m.setInput(m.inputHistory[m.historyIndex])
+				m.recalledInput = m.textInput
+			}
+		}
+		return m, nil
+	case "left":
+		m.moveCursor(-1)
+		return m, nil
+	case "right":
+		m.moveCursor(1)
+		return m, nil
+	case "home", "ctrl+a":
+		m.inputCursor = 0
+		return m, nil
+	case "end", "ctrl+e":
+		m.moveCursor(len(m.textInput))
+		return m, nil
+	case "backspace", "delete":
+		m.deleteInput(msg.String() == "delete")
+		if m.textInput == "" {
+			m.recalledInput = ""
 		}
 		return m, nil
 	default:
-		if len(msg.String()) == 1 {
-			m.textInput += msg.String()
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			m.insertInput(string(msg.Runes))
 		}
 		return m, nil
 	}
 }
 
+// deferPlayback holds a synthesized response until the autoplay delay passes or the user plays it
+func (m *Model) deferPlayback(audioFile string) tea.Cmd {
+	m.pendingAudio = audioFile
+	m.autoplayID++
+	if m.app.config.AutoplayPrompt {
+		m.status = "Press Space to play the response now, Esc to skip"
+	}
+	if m.app.config.AutoplayDelaySeconds <= 0 {
+		return nil
+	}
+	id := m.autoplayID
+	delay := time.Duration(m.app.config.AutoplayDelaySeconds * float64(time.Second))
+	return tea.Tick(delay, func(time.Time) tea.Msg { return autoplayDueMsg{id: id} })
+}
+
+// playPendingAudio starts playing the deferred response
+func (m *Model) playPendingAudio() {
+	audioFile := m.pendingAudio
+	m.pendingAudio = ""
+	m.status = ""
+	go m.app.PlayAudio(audioFile) // Play in background
+}
+
 // handleConversationSubmit handles text input submission
 func (m *Model) handleConversationSubmit() (tea.Model, tea.Cmd) {
 	if strings.TrimSpace(m.textInput) == "" {
@@ -266,11 +902,145 @@ func (m *Model) handleConversationSubmit() (tea.Model, tea.Cmd) {
 	}
 
 	input := strings.TrimSpace(m.textInput)
-	m.textInput = ""
+	m.setInput("")
+	m.recordInputHistory(input)
+	if topic, ok := topicCommand(input); ok {
+		// Overrides the topic for this conversation only; Settings changes the default
+		if topic != "" {
+			m.app.engine.SetTopic(topic)
+		}
+		m.status = "Topic: " + m.app.engine.Topic()
+		return m, nil
+	}
+	input, m.skipCache = noCacheCommand(input)
+	if input == "" {
+		m.status = "Usage: /nocache <message>"
+		return m, nil
+	}
+	if input == "/regenerate" || input == "/retry" {
+		if _, ok := m.app.engine.LastEntry(); !ok {
+			m.status = "Nothing to regenerate"
+			return m, nil
+		}
+		m.uiState = Processing
+		m.error = ""
+		m.recalledInput = ""
+		return m, m.trackRequest(RegenerateCmd(m.newRequestContext(), m.app))
+	}
 	m.uiState = Processing
 	m.error = ""
 
-	return m, ProcessTextCmd(m.app, input)
+	// A recalled input that was changed before sending is logged as an edit of the original turn
+	var editedFrom time.Time
+	if m.recalledInput != "" && input != strings.TrimSpace(m.recalledInput) {
+		if source, ok := m.app.engine.FindEntryByInput(m.recalledInput); ok {
+			editedFrom = source.Timestamp
+		}
+	}
+	m.recalledInput = ""
+
+	if m.app.config.DetectTopicChange && ai.IsTopicChange(input, m.app.engine.History()) {
+		m.pendingInput = input
+		m.pendingEdit = editedFrom
+		m.uiState = ConfirmTopicChange
+		return m, nil
+	}
+
+	return m, m.sendText(input, editedFrom)
+}
+
+// sendText starts a text turn, streaming the reply when StreamResponses is set
+func (m *Model) sendText(input string, editedFrom time.Time) tea.Cmd {
+	ctx := m.newRequestContext()
+	if m.skipCache {
+		ctx = engine.WithoutCache(ctx)
+		m.skipCache = false
+	}
+	if !m.app.config.StreamResponses {
+		return m.trackRequest(ProcessEditedTextCmd(ctx, m.app, input, editedFrom))
+	}
+	m.requestID++
+	return StreamTextCmd(ctx, m.app, input, editedFrom, m.requestID)
+}
+
+// newRequestContext returns the context for a new processing request, which Esc cancels
+func (m *Model) newRequestContext() context.Context {
+	if m.cancelRequest != nil {
+		m.cancelRequest()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelRequest = cancel
+	return ctx
+}
+
+// noCacheCommand parses a "/nocache <message>" input, returning the message and whether it
+// is to bypass the response cache; other inputs are returned as they are
+func noCacheCommand(input string) (string, bool) {
+	rest, ok := strings.CutPrefix(input, "/nocache")
+	if !ok || (rest != "" && rest[0] != ' ') {
+		return input, false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// topicCommand parses a "/topic <subject>" input, returning the subject, which is empty
+// for a bare "/topic", and whether input was the command at all
+func topicCommand(input string) (string, bool) {
+	rest, ok := strings.CutPrefix(input, "/topic")
+	if !ok || (rest != "" && rest[0] != ' ') {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// handleConfirmTopicChangeKeys sends the pending input, after clearing the context on 'y'
+// or with it on 'n', and returns to the conversation with the input restored on Esc
+func (m *Model) handleConfirmTopicChangeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	input, editedFrom := m.pendingInput, m.pendingEdit
+	switch msg.String() {
+	case "y", "Y":
+		m.app.engine.ClearHistory()
+		editedFrom = time.Time{}
+	case "n", "N", "enter":
+	case "esc":
+		m.setInput(input)
+		m.uiState = Conversation
+		m.pendingInput = ""
+		return m, nil
+	default:
+		return m, nil
+	}
+	m.pendingInput = ""
+	m.uiState = Processing
+	return m, m.sendText(input, editedFrom)
+}
+
+// renderConfirmTopicChange renders the offer to start a fresh context
+func (m *Model) renderConfirmTopicChange() string {
+	title := titleStyle.Render("New Topic?")
+	input := statusStyle.Render("You: " + m.pendingInput)
+	prompt := selectedStyle.Render("This looks like a new topic — start a fresh context? y/N")
+	help := helpStyle.Render("y: clear context and send • n/Enter: keep context and send • Esc: back to editing")
+	return lipgloss.JoinVertical(lipgloss.Left, title, "", input, "", prompt, "", help)
+}
+
+// canRecallInput reports whether arrow keys should navigate the input history.
+// This is the case for an empty input, an unedited recalled entry, or the cursor at the
+// start of the line, where a typed draft is kept to come back to.
+func (m *Model) canRecallInput() bool {
+	if m.textInput == "" || m.inputCursor == 0 {
+		return true
+	}
+	return m.historyIndex < len(m.inputHistory) && m.textInput == m.inputHistory[m.historyIndex]
+}
+
+// recordInputHistory adds a submitted input to the session history
+func (m *Model) recordInputHistory(input string) {
+	if n := len(m.inputHistory); n == 0 || m.inputHistory[n-1] != input {
+		m.inputHistory = append(m.inputHistory, input)
+	}
+	m.historyIndex = len(m.inputHistory)
+	m.draftInput = ""
 }
 
 // handleVoiceInput handles voice input
@@ -287,29 +1057,137 @@ func (m *Model) handleVoiceInput() (tea.Model, tea.Cmd) {
 // handleRecordingKeys handles recording state
 func (m *Model) handleRecordingKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
-	case "enter", "space":
+	case "enter", " ":
 		return m.stopRecording()
+	case "p":
+		if !m.recording {
+			return m, nil
+		}
+		paused := m.pausedAt.IsZero()
+		if err := m.app.PauseRecording(paused); err != nil {
+			m.error = err.Error()
+			return m, nil
+		}
+		if paused {
+			m.pausedAt = time.Now()
+		} else {
+			// Move the start past the pause so the elapsed time picks up where it froze
+			m.recordingStart = m.recordingStart.Add(time.Since(m.pausedAt))
+			m.pausedAt = time.Time{}
+		}
+		return m, nil
+	case "ctrl+c":
+		return m.quit()
 	case "q", "esc":
-		m.app.StopRecording()
+		m.error = ""
+		if !m.recording {
+			// The recording is already stopping; drop its audio when it arrives
+			m.discardRecording = true
+			return m, nil
+		}
 		m.recording = false
+		m.app.StopRecording()
 		m.uiState = Conversation
 		return m, nil
 	}
 	return m, nil
 }
 
-// handleProcessingKeys handles processing state
+// quit exits the program, first asking for confirmation when a conversation, recording or
+// playback would be cut off. Quitting from the menus goes straight through.
+func (m *Model) quit() (tea.Model, tea.Cmd) {
+	state := m.app.GetState()
+	inConversation := m.uiState == Conversation || m.uiState == Recording || m.uiState == Processing
+	switch {
+	case state.IsRecording:
+		m.quitReason = "Recording in progress"
+	case state.IsPlaying:
+		m.quitReason = "Playback in progress"
+	case m.uiState == ReviewRecording:
+		m.quitReason = "Your recording hasn't been sent"
+	case m.uiState == Processing || m.streaming:
+		m.quitReason = "A reply is on its way"
+	case inConversation && m.textInput != "":
+		m.quitReason = "Your message hasn't been sent"
+	case inConversation && m.lastResponse != "":
+		m.quitReason = "Conversation in progress"
+	default:
+		return m, tea.Quit
+	}
+	m.quitReturnState = m.uiState
+	m.uiState = ConfirmQuit
+	return m, nil
+}
+
+// handleConfirmQuitKeys quits on 'y' and returns to the previous state on anything else
+func (m *Model) handleConfirmQuitKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "y" || msg.String() == "Y" {
+		return m, tea.Quit
+	}
+	m.uiState = m.quitReturnState
+	if m.uiState == Recording && m.recording {
+		// Ticks stopped while the prompt was up
+		return m, m.tickRecording()
+	}
+	return m, nil
+}
+
+// renderConfirmQuit renders the quit confirmation prompt
+func (m *Model) renderConfirmQuit() string {
+	title := titleStyle.Render("Quit")
+	prompt := errorStyle.Render(fmt.Sprintf("%s — Quit? (y/n)", m.quitReason))
+	return lipgloss.JoinVertical(lipgloss.Left, title, "", prompt)
+}
+
+// cancelledStatus is shown in the conversation view after a turn is cancelled
+const cancelledStatus = "Cancelled, the turn was not completed and nothing was logged"
+
+// handleProcessingKeys handles processing state. Esc cancels the request in flight
+// and returns to the conversation straight away.
 func (m *Model) handleProcessingKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "q", "esc":
-		m.uiState = Conversation
-		return m, nil
+		id := m.requestID
+		return m, func() tea.Msg { return processingCancelledMsg{requestID: id} }
+	case "ctrl+c":
+		return m.quit()
 	}
 	return m, nil
 }
 
-// stopRecording stops recording and processes the audio
+// trackRequest tags the result of a processing command with a new request ID
+// so that results of cancelled requests can be recognised and dropped
+func (m *Model) trackRequest(cmd tea.Cmd) tea.Cmd {
+	m.requestID++
+	id := m.requestID
+	return func() tea.Msg {
+		switch msg := cmd().(type) {
+		case ProcessingCompletedMsg:
+			msg.RequestID = id
+			return msg
+		case ComparisonCompletedMsg:
+			msg.RequestID = id
+			return msg
+		default:
+			return msg
+		}
+	}
+}
+
+// stopRecording stops recording and processes the audio. Recordings shorter than
+// MinRecordingSeconds keep going instead, so an accidental tap doesn't cost an STT call.
+// Enter, Space and detected silence all end up here, and only the first stops the recorder.
 func (m *Model) stopRecording() (tea.Model, tea.Cmd) {
+	if !m.recording {
+		return m, nil
+	}
+	min := time.Duration(m.app.config.MinRecordingSeconds * float64(time.Second))
+	if m.recordedTime(time.Now()) < min {
+		m.error = "Recording too short — keep going or press Esc to cancel"
+		return m, nil
+	}
+	m.error = ""
+	m.recording = false
 	return m, StopRecordingCmd(m.app)
 }
 
@@ -336,6 +1214,30 @@ func (m *Model) View() string {
 		return m.renderAPIKeyInput()
 	case APIKeyVerifying:
 		return m.renderAPIKeyVerifying()
+	case ComparisonSetup:
+		return m.renderComparisonSetup()
+	case Comparison:
+		return m.renderComparison()
+	case History:
+		return m.renderHistory()
+	case TagInput:
+		return m.renderTagInput()
+	case TopicInput:
+		return m.renderTopicInput()
+	case ConfirmQuit:
+		return m.renderConfirmQuit()
+	case StartupWizard:
+		return m.renderStartupWizard()
+	case ConfirmTopicChange:
+		return m.renderConfirmTopicChange()
+	case SessionSummary:
+		return m.renderSessionSummary()
+	case Health:
+		return m.renderHealth()
+	case ReviewRecording:
+		return m.renderReviewRecording()
+	case Passphrase:
+		return m.renderPassphrase()
 	default:
 		return "Unknown state"
 	}
@@ -346,9 +1248,8 @@ func (m *Model) renderMainMenu() string {
 	title := titleStyle.Render("JORK - AI Communication Assistant")
 
 	state := m.app.GetState()
-	status := fmt.Sprintf("Mode: %s | Knowledge Level: %s",
-		state.CurrentMode.String(),
-		state.KnowledgeLevel.String())
+	status := m.statusLine(state)
+	levelHint := descriptionStyle.Render(state.KnowledgeLevel.Description())
 
 	menu := `
 1. Select Communication Mode
@@ -356,17 +1257,21 @@ func (m *Model) renderMainMenu() string {
 3. Start Conversation
 4. View Conversation History
 5. Settings
+6. Session Summary
+7. Service Health
 
 Press 'q' to quit`
 
-	return lipgloss.JoinVertical(
-		lipgloss.Center,
-		title,
-		"",
-		statusStyle.Render(status),
-		"",
-		menuStyle.Render(menu),
-	)
+	parts := []string{title, "", statusStyle.Render(status), levelHint, ""}
+	if m.error != "" {
+		parts = append(parts, errorStyle.Render("Error: "+m.error), "")
+	}
+	if report, ok := m.app.LastHealth(); ok && !report.Healthy() {
+		parts = append(parts, errorStyle.Render("● The last health check failed, press 7 for details"), "")
+	}
+	parts = append(parts, menuStyle.Render(menu))
+
+	return lipgloss.JoinVertical(lipgloss.Center, parts...)
 }
 
 // renderModeSelection renders the mode selection screen
@@ -416,6 +1321,7 @@ func (m *Model) renderKnowledgeLevelSelection() string {
 	for i, level := range levels {
 		if i == m.selectedLevel {
 			items = append(items, selectedStyle.Render("> "+level))
+			items = append(items, descriptionStyle.Render("    "+models.KnowledgeLevel(i).Description()))
 		} else {
 			items = append(items, "  "+level)
 		}
@@ -433,18 +1339,73 @@ func (m *Model) renderKnowledgeLevelSelection() string {
 	)
 }
 
+// statusLine summarizes the session for the main menu and conversation headers: its title,
+// mode and level, and the topic and stateless mode when they aren't the defaults
+func (m *Model) statusLine(state *models.AppState) string {
+	status := fmt.Sprintf("Mode: %s | Knowledge Level: %s",
+		state.CurrentMode.String(),
+		state.KnowledgeLevel.String())
+	if topic := m.app.engine.Topic(); topic != "general" {
+		status += " | Topic: " + topic
+	}
+	if state.Stateless {
+		status += " | stateless"
+	}
+	if state.Title != "" {
+		status = state.Title + " | " + status
+	}
+	return status
+}
+
+// conversationShortcuts ends the conversation help in every mode
+const conversationShortcuts = "Ctrl+L to compare levels, Ctrl+T to tag turns, Ctrl+Y to copy the reply, Ctrl+S to toggle stateless, /topic to set the topic, /regenerate to retry the last reply. Esc to go back."
+
 // renderConversation renders the conversation interface
 func (m *Model) renderConversation() string {
 	state := m.app.GetState()
 	title := titleStyle.Render("Conversation")
 
-	status := fmt.Sprintf("Mode: %s | Knowledge Level: %s",
-		state.CurrentMode.String(),
-		state.KnowledgeLevel.String())
+	status := m.statusLine(state)
+	levelHint := descriptionStyle.Render(state.KnowledgeLevel.Description())
+
+	// Long lines wrap to the terminal, and the response scrolls in what the wrapped input and help leave
+	width := m.width - boxChrome
+	inputText := wrapText("You: "+m.inputWithCursor(), width)
+	helpText := "Type your message and press Enter. ↑/↓ to recall previous input. " + conversationShortcuts
+	if state.CurrentMode == models.VoiceToText || state.CurrentMode == models.VoiceToVoice {
+		helpText = "Type your message and press Enter, or press Ctrl+R for voice input. " + conversationShortcuts
+	}
+	helpText = wrapText(helpText, m.width)
+	input := inputStyle.Render(inputText)
+	help := helpStyle.Render(helpText)
+	if m.height > 0 {
+		extra := strings.Count(inputText, "\n") + strings.Count(helpText, "\n")
+		m.convView.setHeight(max(m.height-conversationChrome-extra, minViewportHeight))
+	}
 
+	name := m.app.config.AssistantNameFor(state.KnowledgeLevel)
 	var response string
-	if m.lastResponse != "" {
-		response = responseStyle.Render("AI: " + m.lastResponse)
+	if m.lastResponse != "" || m.streaming {
+		text := m.lastResponse
+		if m.streaming && m.caretVisible && m.app.config.StreamingCaret {
+			text += streamingCaret
+		}
+		m.convView.setContent(m.renderReply(name, text, width))
+		response = responseStyle.Render(m.convView.view())
+		if hint := m.convView.scrollHint(); hint != "" {
+			response = lipgloss.JoinVertical(lipgloss.Left, response, descriptionStyle.Render(hint+" • PgUp/PgDn to scroll"))
+		}
+		if last, ok := m.app.engine.LastEntry(); ok && last.AIResponse == m.lastResponse {
+			// Voice turns always echo the transcription so it can be checked
+			if m.app.config.EchoInput || last.IsVoiceInput {
+				response = lipgloss.JoinVertical(lipgloss.Left, statusStyle.Render("You: "+models.Truncate(last.UserInput, echoPreviewLength)), response)
+			}
+			if last.OutOfCharacter {
+				response = lipgloss.JoinVertical(lipgloss.Left, response, descriptionStyle.Render(outOfCharacterMarker))
+			}
+		}
+	} else if m.app.config.Greeting != "" && len(state.ConversationLog) == 0 {
+		response = responseStyle.Render(wrapText(name+": "+m.app.config.Greeting, width))
 	}
 
 	var errorMsg string
@@ -452,16 +1413,7 @@ func (m *Model) renderConversation() string {
 		errorMsg = errorStyle.Render("Error: " + m.error)
 	}
 
-	input := inputStyle.Render("You: " + m.textInput + "█")
-
-	var help string
-	if state.CurrentMode == models.VoiceToText || state.CurrentMode == models.VoiceToVoice {
-		help = helpStyle.Render("Type your message and press Enter, or press Ctrl+R for voice input. Esc to go back.")
-	} else {
-		help = helpStyle.Render("Type your message and press Enter. Esc to go back.")
-	}
-
-	parts := []string{title, "", statusStyle.Render(status), ""}
+	parts := []string{title, "", statusStyle.Render(status), levelHint, ""}
 
 	if response != "" {
 		parts = append(parts, response, "")
@@ -471,6 +1423,18 @@ func (m *Model) renderConversation() string {
 		parts = append(parts, errorMsg, "")
 	}
 
+	if tags := m.app.engine.Tags(); len(tags) > 0 {
+		parts = append(parts, statusStyle.Render("Tags: "+strings.Join(tags, ", ")))
+	}
+
+	if m.status != "" {
+		parts = append(parts, statusStyle.Render(m.status))
+	}
+
+	if m.copyStatus != "" {
+		parts = append(parts, statusStyle.Render(m.copyStatus))
+	}
+
 	parts = append(parts, input, "", help)
 
 	return lipgloss.JoinVertical(lipgloss.Left, parts...)
@@ -479,63 +1443,235 @@ func (m *Model) renderConversation() string {
 // renderRecording renders the recording interface
 func (m *Model) renderRecording() string {
 	title := titleStyle.Render("Recording...")
+	if !m.pausedAt.IsZero() {
+		title = titleStyle.Render("Recording paused")
+	}
 
 	duration := recordingStyle.Render(fmt.Sprintf("Duration: %.1fs", m.recordingTime.Seconds()))
+	if left := m.maxRecordingTime() - m.recordingTime; m.maxRecordingTime() > 0 && left <= recordingCountdown {
+		duration = recordingStyle.Render(fmt.Sprintf("Duration: %.1fs (stopping in %.0fs)", m.recordingTime.Seconds(), max(left, 0).Seconds()))
+	}
+	meter := levelMeter(m.inputLevel)
+
+	help := helpStyle.Render("Press Enter or Space to stop recording, P to pause or resume, Esc to cancel")
+
+	parts := []string{title, "", duration, meter, ""}
+	if m.status != "" {
+		parts = append(parts, statusStyle.Render(m.status))
+	}
+	if m.error != "" {
+		parts = append(parts, errorStyle.Render(m.error), "")
+	}
+	parts = append(parts, help)
+
+	return lipgloss.JoinVertical(lipgloss.Center, parts...)
+}
+
+// handleReviewRecordingKeys plays, sends or discards the recording under review
+func (m *Model) handleReviewRecordingKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "p", " ":
+		if err := m.app.PlayRecording(m.reviewAudio); err != nil {
+			m.error = err.Error()
+		} else {
+			m.error = ""
+		}
+		return m, nil
+	case "enter", "s":
+		audioData := m.reviewAudio
+		m.reviewAudio = nil
+		m.app.StopAudio()
+		m.error = ""
+		m.uiState = Processing
+		return m, m.processVoiceInput(audioData)
+	case "d", "esc":
+		m.reviewAudio = nil
+		m.app.StopAudio()
+		m.error = ""
+		m.status = "Recording discarded"
+		m.uiState = Conversation
+		return m, nil
+	case "ctrl+c":
+		return m.quit()
+	}
+	return m, nil
+}
 
-	help := helpStyle.Render("Press Enter or Space to stop recording, Esc to cancel")
+// renderReviewRecording renders the choice of what to do with a finished recording
+func (m *Model) renderReviewRecording() string {
+	title := titleStyle.Render("Review Recording")
+	var length time.Duration
+	if m.reviewAudio != nil {
+		length = m.reviewAudio.Duration
+	}
+	duration := recordingStyle.Render(fmt.Sprintf("Duration: %.1fs", length.Seconds()))
+	help := helpStyle.Render("P: Play • Enter: Send • D: Discard")
+
+	parts := []string{title, "", duration, ""}
+	if m.error != "" {
+		parts = append(parts, errorStyle.Render(m.error), "")
+	}
+	parts = append(parts, help)
+	return lipgloss.JoinVertical(lipgloss.Center, parts...)
+}
+
+// renderProcessing renders the processing interface
+func (m *Model) renderProcessing() string {
+	title := titleStyle.Render("Processing...")
+
+	spinner := processingStyle.Render("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+	help := helpStyle.Render("Please wait... Esc to cancel")
 
 	return lipgloss.JoinVertical(
 		lipgloss.Center,
 		title,
 		"",
-		duration,
+		spinner,
 		"",
 		help,
 	)
 }
 
-// renderProcessing renders the processing interface
-func (m *Model) renderProcessing() string {
-	title := titleStyle.Render("Processing...")
+// renderStartupWizard renders the initial configuration wizard UI
+func (m *Model) renderStartupWizard() string {
+	title := titleStyle.Render("Welcome to JORK")
 
-	spinner := processingStyle.Render("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+	intro := `JORK helps you practise explaining things: the AI plays a listener
+at the knowledge level you choose and asks questions where you lose it.`
+
+	state := m.app.GetState()
+	defaults := statusStyle.Render(fmt.Sprintf("Mode: %s | Knowledge Level: %s | Voice: %s",
+		state.CurrentMode.String(), state.KnowledgeLevel.String(), m.app.config.TTSTargetVoice))
+
+	var errorMsg string
+	if m.error != "" {
+		errorMsg = errorStyle.Render("Error: " + m.error)
+	}
+
+	help := helpStyle.Render("Enter to finish setup, 's' to adjust settings first, Esc to skip for now")
+
+	return lipgloss.JoinVertical(lipgloss.Left, title, "", intro, "", defaults, errorMsg, help)
+}
 
-	help := helpStyle.Render("Please wait...")
+// Screen lines around the scrolling part of the history and conversation views,
+// and the fewest lines a view shows however small the terminal
+const (
+	historyChrome      = 9
+	conversationChrome = 14
+	minViewportHeight  = 3
+)
+
+// echoPreviewLength is the number of characters of the last input echoed above the response
+const echoPreviewLength = 120
+
+// outOfCharacterMarker flags responses a strict persona check judged out of character
+const outOfCharacterMarker = "⚠ may have broken character"
+
+// formatConversationHistory formats the conversation history for display,
+// limited to entries carrying m.historyFilter when it is set. It also returns
+// the range of lines showing the selected entry.
+func (m *Model) formatConversationHistory() (string, int, int) {
+	state := m.app.GetState()
+	if len(state.ConversationLog) == 0 {
+		return "No conversation history", 0, 0
+	}
+
+	var history []string
+	var start, end int
+	var previous *models.ConversationEntry
+	for i, entry := range state.ConversationLog {
+		if m.historyFilter != "" && !entry.HasTag(m.historyFilter) {
+			continue
+		}
+		// Mark where the knowledge level changed between displayed entries
+		if previous != nil && previous.KnowledgeLevel != entry.KnowledgeLevel {
+			history = append(history, dividerStyle.Render(fmt.Sprintf("— switched to %s —", entry.KnowledgeLevel.String())), "")
+		}
+		previous = &state.ConversationLog[i]
+		timestamp := entry.Timestamp.Format("15:04:05")
+		edited := ""
+		if !entry.EditedFrom.IsZero() {
+			edited = " (edited from earlier)"
+		}
+		lines := []string{
+			fmt.Sprintf("[%s] You: %s%s", timestamp, entry.UserInput, edited),
+			fmt.Sprintf("[%s] %s: %s", timestamp, m.app.config.AssistantNameFor(entry.KnowledgeLevel), entry.AIResponse),
+		}
+		if entry.OutOfCharacter {
+			lines = append(lines, descriptionStyle.Render(outOfCharacterMarker))
+		}
+		if m.app.config.ShowTokenUsage && (entry.InputTokens > 0 || entry.OutputTokens > 0) {
+			lines = append(lines, descriptionStyle.Render(m.usageAnnotation(entry)))
+		}
+		if entry.Model != "" && entry.Model != m.app.openaiClient.Model {
+			lines = append(lines, descriptionStyle.Render("answered by "+entry.Model))
+		}
+		if len(entry.Tags) > 0 {
+			lines = append(lines, fmt.Sprintf("Tags: %s", strings.Join(entry.Tags, ", ")))
+		}
+		if entry.Excluded {
+			lines = append(lines, "(excluded from context)")
+			for j := range lines {
+				lines[j] = excludedStyle.Render(lines[j])
+			}
+		}
+		marker := "  "
+		if i == m.historyCursor {
+			marker = "▶ "
+			start = lineCount(history)
+		}
+		for _, line := range lines {
+			history = append(history, marker+line)
+		}
+		if i == m.historyCursor {
+			end = lineCount(history)
+		}
+		history = append(history, "")
+	}
 
-	return lipgloss.JoinVertical(
-		lipgloss.Center,
-		title,
-		"",
-		spinner,
-		"",
-		help,
-	)
+	return strings.Join(history, "\n"), start, end
 }
 
-// renderStartupWizard renders the initial configuration wizard UI
-
-// formatConversationHistory formats the conversation history for display
-func (m *Model) formatConversationHistory() string {
-	state := m.app.GetState()
-	if len(state.ConversationLog) == 0 {
-		return "No conversation history"
+// usageAnnotation summarizes the tokens an entry used and what they cost at the configured rates
+func (m *Model) usageAnnotation(entry models.ConversationEntry) string {
+	tokens := fmt.Sprintf("↑%d ↓%d", entry.InputTokens, entry.OutputTokens)
+	if m.app.config.InputTokenCost == 0 && m.app.config.OutputTokenCost == 0 {
+		return "[" + tokens + "]"
+	}
+	cost := (float64(entry.InputTokens)*m.app.config.InputTokenCost + float64(entry.OutputTokens)*m.app.config.OutputTokenCost) / 1e6
+	if cost < 0.01 {
+		return fmt.Sprintf("[%s, <$0.01]", tokens)
 	}
+	return fmt.Sprintf("[%s, $%.2f]", tokens, cost)
+}
 
-	var history []string
-	for _, entry := range state.ConversationLog {
-		timestamp := entry.Timestamp.Format("15:04:05")
-		history = append(history, fmt.Sprintf("[%s] You: %s", timestamp, entry.UserInput))
-		history = append(history, fmt.Sprintf("[%s] AI: %s", timestamp, entry.AIResponse))
-		history = append(history, "")
+// visibleHistory returns the log indexes of the entries the history view currently shows
+func (m *Model) visibleHistory() []int {
+	var visible []int
+	for i, entry := range m.app.GetState().ConversationLog {
+		if m.historyFilter == "" || entry.HasTag(m.historyFilter) {
+			visible = append(visible, i)
+		}
 	}
+	return visible
+}
 
-	return strings.Join(history, "\n")
+// selectLastHistoryEntry selects the most recent entry the history view shows
+func (m *Model) selectLastHistoryEntry() {
+	m.historyCursor = -1
+	if visible := m.visibleHistory(); len(visible) > 0 {
+		m.historyCursor = visible[len(visible)-1]
+	}
+	m.historyView.gotoBottom()
+	m.historyToCursor = true
 }
 
 // Commands and messages
 
 type recordingTickMsg struct {
-	duration time.Duration
+	at      time.Time
+	session int
 }
 
 type processingDoneMsg struct {
@@ -543,12 +1679,76 @@ type processingDoneMsg struct {
 	error    string
 }
 
+// streamingCaret marks the end of a response that is still being generated
+const streamingCaret = "▍"
+
+type caretTickMsg struct {
+	stream int
+}
+
+// startStreamIndicator marks a response stream as open and starts blinking the typing caret
+func (m *Model) startStreamIndicator() tea.Cmd {
+	m.streaming = true
+	m.streamID++
+	m.caretVisible = true
+	return m.tickCaret()
+}
+
+// stopStreamIndicator marks the response stream as closed, removing the caret
+func (m *Model) stopStreamIndicator() {
+	m.streaming = false
+	m.caretVisible = false
+}
+
+// tickCaret schedules the next blink of the typing caret for the current stream
+func (m *Model) tickCaret() tea.Cmd {
+	stream := m.streamID
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
+		return caretTickMsg{stream: stream}
+	})
+}
+
+// tickRecording schedules the next display update for the current recording session
 func (m *Model) tickRecording() tea.Cmd {
+	session := m.recordingID
 	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg {
-		return recordingTickMsg{duration: m.recordingTime + 100*time.Millisecond}
+		return recordingTickMsg{at: t, session: session}
 	})
 }
 
+// levelMeterWidth is the number of cells in the recording level meter
+const levelMeterWidth = 30
+
+// levelMeter renders the input level as a horizontal bar
+func levelMeter(level float32) string {
+	filled := int(audio.LevelFraction(level)*levelMeterWidth + 0.5)
+	return "Level: " + strings.Repeat("█", filled) + strings.Repeat("░", levelMeterWidth-filled)
+}
+
+// recordingCountdown is how close to MaxRecordingSeconds the recording screen starts counting down
+const recordingCountdown = 10 * time.Second
+
+// maxRecordingTime returns the longest a recording may run, or 0 for no limit
+func (m *Model) maxRecordingTime() time.Duration {
+	return time.Duration(m.app.config.MaxRecordingSeconds * float64(time.Second))
+}
+
+// recordedTime returns how long the current recording has captured audio by now, leaving out pauses
+func (m *Model) recordedTime(now time.Time) time.Duration {
+	if !m.pausedAt.IsZero() {
+		now = m.pausedAt
+	}
+	return recordingElapsed(m.recordingStart, now)
+}
+
+// recordingElapsed returns the wall-clock time recorded between start and now
+func recordingElapsed(start, now time.Time) time.Duration {
+	if start.IsZero() || now.Before(start) {
+		return 0
+	}
+	return now.Sub(start)
+}
+
 // APIKeyValidationDoneMsg indicates result of API key validation
 type APIKeyValidationDoneMsg struct {
 	err error
@@ -565,7 +1765,7 @@ func ValidateAPIKeyCmd(app *App, apiKey string) tea.Cmd {
 
 // processVoiceInput creates a command to process voice input
 func (m *Model) processVoiceInput(audioData *models.AudioData) tea.Cmd {
-	return ProcessVoiceCmd(m.app, audioData)
+	return m.trackRequest(ProcessVoiceCmd(m.newRequestContext(), m.app, audioData))
 }
 
 // Add key handling for the Startup Wizard state
@@ -573,8 +1773,17 @@ func (m *Model) handleStartupWizardKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "enter":
 		// Finish the setup wizard and return to the main menu
+		if err := m.app.config.MarkSetupCompleted(); err != nil {
+			m.error = err.Error()
+			return m, nil
+		}
+		m.error = ""
 		m.uiState = MainMenu
 		return m, nil
+	case "s":
+		// Settings can be changed first; the wizard shows again next run until finished
+		m.uiState = Settings
+		return m, nil
 	case "esc", "q":
 		m.uiState = MainMenu
 		return m, nil
@@ -583,6 +1792,23 @@ func (m *Model) handleStartupWizardKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// setModelOptions fills the model editor with the available models, or the defaults when
+// none are known, keeping the cursor on the current model
+func (m *Model) setModelOptions() {
+	if len(m.app.config.AvailableModels) > 0 {
+		m.editOptions = m.app.config.AvailableModels
+	} else {
+		m.editOptions = []string{"gpt-4", "claude-3-5-sonnet-20241022"}
+	}
+	m.cursor = 0
+	for i, option := range m.editOptions {
+		if option == m.app.config.ConversationModel {
+			m.cursor = i
+			break
+		}
+	}
+}
+
 // Styles
 func (m *Model) renderSettings() string {
 	title := titleStyle.Render("Settings")
@@ -601,7 +1827,14 @@ func (m *Model) renderSettings() string {
 		encryptStr = "On"
 	}
 	settings = append(settings, fmt.Sprintf("Encrypt Settings: %s", encryptStr))
-	settings = append(settings, "OpenAI API Key: ****")
+	if m.app.config.OpenAIAPIKey == "" {
+		settings = append(settings, "OpenAI API Key: (not set)")
+	} else {
+		settings = append(settings, "OpenAI API Key: ****")
+	}
+	settings = append(settings, fmt.Sprintf("Topic: %s", m.app.config.Topic))
+	settings = append(settings, fmt.Sprintf("Input Device: %s", m.app.config.InputDevice))
+	settings = append(settings, fmt.Sprintf("STT Language: %s", sttLanguageOption(m.app.config.STTLanguage)))
 
 	// Render each setting, highlighting the selected one
 	var renderedItems []string
@@ -613,8 +1846,27 @@ func (m *Model) renderSettings() string {
 		}
 	}
 
-	help := helpStyle.Render("↑/↓ to navigate, Enter to edit value, 'v' to sample TTS voice, Esc to return")
-	return lipgloss.JoinVertical(lipgloss.Center, title, "", strings.Join(renderedItems, "\n"), "", help)
+	help := helpStyle.Render("↑/↓ to navigate, Enter to edit value, 'v' to sample TTS voice, 'V' to compare all voices, 'r' to refresh models, 't' to play a test tone, Esc to return")
+	parts := []string{title, "", strings.Join(renderedItems, "\n"), ""}
+	if m.settingsStatus != "" {
+		parts = append(parts, statusStyle.Render(m.settingsStatus), "")
+	}
+	parts = append(parts, help)
+	return lipgloss.JoinVertical(lipgloss.Center, parts...)
+}
+
+// autoDetectLanguage is the STT language option that leaves the language to be detected
+const autoDetectLanguage = "auto"
+
+// sttLanguageOptions are the STT language choices offered in Settings, as ISO-639-1 codes
+var sttLanguageOptions = []string{autoDetectLanguage, "en", "es", "fr", "de", "it", "pt", "nl", "pl", "ru", "uk", "tr", "ar", "hi", "ja", "ko", "zh"}
+
+// sttLanguageOption returns the Settings option for the configured STT language
+func sttLanguageOption(language string) string {
+	if language == "" {
+		return autoDetectLanguage
+	}
+	return language
 }
 
 func (m *Model) handleSettingsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -625,17 +1877,33 @@ func (m *Model) handleSettingsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 	case "down", "j":
-		if m.selectedSetting < 7 {
+		if m.selectedSetting < 10 {
 			m.selectedSetting++
 		}
 		return m, nil
 	case "esc", "q":
+		// Toggles like Encrypt Settings aren't saved as they change, so save on the way out
+		if err := m.app.config.Save(); err != nil {
+			m.error = "Failed to save settings: " + err.Error()
+		}
 		m.uiState = MainMenu
 		return m, nil
-	case "v":
-		// Always stop any playing audio and reset flag, then play new sample.
+	case "V":
+		// Play a sample of every voice in order
 		_ = m.app.StopAudio()
-		m.isSamplingVoice = false
+		go func() {
+			_ = m.app.CompareVoices()
+		}()
+		return m, nil
+	case "r":
+		m.settingsStatus = "Refreshing models..."
+		return m, RefreshModelsCmd(m.app, true)
+	case "t":
+		m.settingsStatus = "Playing test tone..."
+		return m, TestOutputCmd(m.app)
+	case "v":
+		// PlayAudioSample stops whatever is playing before starting the new sample
+		m.isSamplingVoice = true
 		go func() {
 			_ = m.app.PlayAudioSample()
 			m.isSamplingVoice = false
@@ -645,33 +1913,35 @@ func (m *Model) handleSettingsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// If the selected setting is "Encrypt Settings", toggle its value.
 		if m.selectedSetting == 6 {
 			m.app.config.EncryptSettings = !m.app.config.EncryptSettings
+			if m.app.config.NeedsPassphrase() {
+				m.startNewPassphrase(Settings)
+			}
+			return m, nil
 		} else if m.selectedSetting == 7 {
 			m.editTitle = "Enter OpenAI API Key"
 			m.editOptions = []string{m.app.config.OpenAIAPIKey}
 			m.cursor = 0
+		} else if m.selectedSetting == 8 {
+			m.topicInput = m.app.config.Topic
+			m.uiState = TopicInput
+			return m, nil
 		} else {
 			// For other settings, enter the editing dialog and set cursor to the current value.
 			switch m.selectedSetting {
 			case 0:
 				m.editTitle = "Select Conversation Model"
-				// If the available models list is empty, fetch models synchronously.
-				if len(m.app.config.AvailableModels) == 0 {
-					if models, err := m.app.openaiClient.FetchAvailableModels(); err == nil && len(models) > 0 {
-						m.app.config.AvailableModels = models
-					}
-				}
-				// Use the fetched models if available; fallback to defaults otherwise.
-				if len(m.app.config.AvailableModels) > 0 {
-					m.editOptions = m.app.config.AvailableModels
-				} else {
-					m.editOptions = []string{"gpt-4", "claude-3-5-sonnet-20241022"}
+				// Use the cached list right away and refresh it in the background when stale
+				var refresh tea.Cmd
+				cached, stale := m.app.CachedModels()
+				if len(cached) > 0 {
+					m.app.config.AvailableModels = cached
 				}
-				for i, option := range m.editOptions {
-					if option == m.app.config.ConversationModel {
-						m.cursor = i
-						break
-					}
+				if stale {
+					refresh = RefreshModelsCmd(m.app, false)
 				}
+				m.setModelOptions()
+				m.uiState = SettingsEdit
+				return m, refresh
 			case 1:
 				m.editTitle = "Select TTS Model"
 				m.editOptions = []string{"tts-1", "tts-2"}
@@ -723,6 +1993,29 @@ func (m *Model) handleSettingsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 						}
 					}
 				}
+			case 9:
+				m.editTitle = "Select Input Device"
+				devices, err := audio.ListInputDevices()
+				if err != nil {
+					m.settingsStatus = "Could not list input devices: " + err.Error()
+				}
+				m.editOptions = append([]string{audio.DefaultDevice}, devices...)
+				m.cursor = 0
+				for i, option := range m.editOptions {
+					if option == m.app.config.InputDevice {
+						m.cursor = i
+						break
+					}
+				}
+			case 10:
+				m.editTitle = "Select STT Language"
+				current := sttLanguageOption(m.app.config.STTLanguage)
+				m.editOptions = sttLanguageOptions
+				if !slices.Contains(m.editOptions, current) {
+					// Keep a language set in the config file selectable
+					m.editOptions = append(slices.Clone(m.editOptions), current)
+				}
+				m.cursor = slices.Index(m.editOptions, current)
 			}
 		}
 		m.uiState = SettingsEdit
@@ -775,6 +2068,22 @@ var (
 	processingStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("86")).
 			Bold(true)
+
+	descriptionStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("245")).
+				Italic(true)
+
+	excludedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240"))
+
+	dividerStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Italic(true)
+
+	comparisonStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("39")).
+			Padding(0, 1)
 )
 
 func (m *Model) handleSettingsEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -790,6 +2099,7 @@ func (m *Model) handleSettingsEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 	case "enter":
+		var cmd tea.Cmd
 		switch m.selectedSetting {
 		case 0:
 			m.app.config.ConversationModel = m.editOptions[m.cursor]
@@ -802,26 +2112,35 @@ func (m *Model) handleSettingsEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case 4:
 			if val, err := strconv.Atoi(m.editOptions[m.cursor]); err == nil {
 				m.app.config.ResponseVerbosity = val
+				m.app.engine.SetVerbosity(val)
 			}
 		case 5:
 			if val, err := strconv.Atoi(m.editOptions[m.cursor]); err == nil {
 				m.app.config.SpeechSpeed = val
+				m.app.ttsClient.SetSpeed(val)
 			}
 		case 7:
 			m.app.config.OpenAIAPIKey = m.editOptions[m.cursor]
-			// Trigger health check after updating the API key
-			if err := m.app.HealthCheck(); err != nil {
-				m.error = "Health Check failed: " + err.Error()
-			} else {
-				m.error = "Health Check passed"
+			// Check the new key in the background, even if a check with the old one is running
+			m.settingsStatus = "Checking the new key..."
+			m.healthChecking = true
+			m.reportHealth = true
+			cmd = HealthCheckCmd(m.app)
+		case 9:
+			m.app.SetInputDevice(m.editOptions[m.cursor])
+		case 10:
+			language := m.editOptions[m.cursor]
+			if language == autoDetectLanguage {
+				language = ""
 			}
+			m.app.SetSTTLanguage(language)
 		}
 		// Save the updated settings to disk.
 		if err := m.app.config.Save(); err != nil {
 			m.error = "Failed to save settings: " + err.Error()
 		}
 		m.uiState = Settings
-		return m, nil
+		return m, cmd
 	case "esc", "q":
 		m.uiState = Settings
 		return m, nil
@@ -889,3 +2208,388 @@ func (m *Model) renderSettingsEdit() string {
  	help := helpStyle.Render("Verifying and performing health checks, please wait...")
  	return lipgloss.JoinVertical(lipgloss.Center, title, "", help)
  }
+
+// startComparison opens the level picker for comparing the last input
+func (m *Model) startComparison() (tea.Model, tea.Cmd) {
+	if m.app.state.LastMessage == "" {
+		m.error = "Nothing to compare yet, send a message first"
+		return m, nil
+	}
+	current := int(m.app.state.KnowledgeLevel)
+	other := int(models.Child)
+	if current == other {
+		other = int(models.CoWorker)
+	}
+	m.compareLevels = [2]int{current, other}
+	m.compareColumn = 0
+	m.uiState = ComparisonSetup
+	return m, nil
+}
+
+// handleComparisonSetupKeys handles picking the two levels to compare
+func (m *Model) handleComparisonSetupKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.uiState = Conversation
+		return m, nil
+	case "left", "h":
+		m.compareColumn = 0
+		return m, nil
+	case "right", "l", "tab":
+		m.compareColumn = 1
+		return m, nil
+	case "up", "k":
+		if m.compareLevels[m.compareColumn] > 0 {
+			m.compareLevels[m.compareColumn]--
+		}
+		return m, nil
+	case "down", "j":
+		if m.compareLevels[m.compareColumn] < 3 {
+			m.compareLevels[m.compareColumn]++
+		}
+		return m, nil
+	case "enter":
+		m.uiState = Processing
+		m.error = ""
+		return m, m.trackRequest(CompareLevelsCmd(
+			m.app,
+			m.app.state.LastMessage,
+			models.KnowledgeLevel(m.compareLevels[0]),
+			models.KnowledgeLevel(m.compareLevels[1]),
+		))
+	}
+	return m, nil
+}
+
+// handleComparisonKeys handles the comparison result view
+func (m *Model) handleComparisonKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc", "enter":
+		m.uiState = Conversation
+		return m, nil
+	}
+	return m, nil
+}
+
+// renderComparisonSetup renders the two level pickers
+func (m *Model) renderComparisonSetup() string {
+	title := titleStyle.Render("Compare Knowledge Levels")
+
+	var columns []string
+	for col, selected := range m.compareLevels {
+		var items []string
+		for i := 0; i <= int(models.CoWorker); i++ {
+			level := models.KnowledgeLevel(i).String()
+			if i == selected {
+				items = append(items, selectedStyle.Render("> "+level))
+			} else {
+				items = append(items, "  "+level)
+			}
+		}
+		style := comparisonStyle
+		if col == m.compareColumn {
+			style = style.BorderForeground(lipgloss.Color("86"))
+		}
+		columns = append(columns, style.Render(strings.Join(items, "\n")))
+	}
+
+	help := helpStyle.Render("←/→ to switch side, ↑/↓ to pick a level, Enter to compare, Esc to go back")
+
+	return lipgloss.JoinVertical(
+		lipgloss.Center,
+		title,
+		"",
+		lipgloss.JoinHorizontal(lipgloss.Top, columns...),
+		"",
+		help,
+	)
+}
+
+// renderComparison renders both responses side by side
+func (m *Model) renderComparison() string {
+	title := titleStyle.Render("Compare Knowledge Levels")
+	prompt := statusStyle.Render("You: " + m.app.state.LastMessage)
+
+	width := (m.width - 6) / 2
+	if width < 20 {
+		width = 20
+	}
+	style := comparisonStyle.Width(width)
+	first := style.Render(selectedStyle.Render(models.KnowledgeLevel(m.compareLevels[0]).String()) + "\n\n" + m.compareFirst)
+	second := style.Render(selectedStyle.Render(models.KnowledgeLevel(m.compareLevels[1]).String()) + "\n\n" + m.compareSecond)
+
+	help := helpStyle.Render("Press Enter or Esc to return to the conversation")
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		prompt,
+		lipgloss.JoinHorizontal(lipgloss.Top, first, second),
+		help,
+	)
+}
+
+// handleHistoryKeys handles the conversation history view
+func (m *Model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.uiState = MainMenu
+		return m, nil
+	case "t":
+		// Cycle the filter through every known tag, then back to all entries
+		tags := m.app.engine.KnownTags()
+		next := ""
+		for i, tag := range tags {
+			if m.historyFilter == "" {
+				next = tags[0]
+				break
+			}
+			if tag == m.historyFilter {
+				if i+1 < len(tags) {
+					next = tags[i+1]
+				}
+				break
+			}
+		}
+		m.historyFilter = next
+		m.selectLastHistoryEntry()
+		return m, nil
+	case "pgup":
+		m.historyView.pageUp()
+		return m, nil
+	case "pgdown":
+		m.historyView.pageDown()
+		return m, nil
+	case "up", "k", "down", "j":
+		m.historyToCursor = true
+		visible := m.visibleHistory()
+		for i, index := range visible {
+			if index != m.historyCursor {
+				continue
+			}
+			if (msg.String() == "up" || msg.String() == "k") && i > 0 {
+				m.historyCursor = visible[i-1]
+			} else if (msg.String() == "down" || msg.String() == "j") && i+1 < len(visible) {
+				m.historyCursor = visible[i+1]
+			}
+			break
+		}
+		return m, nil
+	case "x":
+		// Toggle whether the selected entry is sent as context
+		entries := m.app.GetState().ConversationLog
+		if m.historyCursor >= 0 && m.historyCursor < len(entries) {
+			entry := entries[m.historyCursor]
+			m.app.engine.SetExcluded(entry.Timestamp, !entry.Excluded)
+		}
+		return m, nil
+	case "c":
+		entries := m.app.GetState().ConversationLog
+		if m.historyCursor >= 0 && m.historyCursor < len(entries) && entries[m.historyCursor].AIResponse != "" {
+			return m, CopyToClipboardCmd(entries[m.historyCursor].AIResponse)
+		}
+		return m, nil
+	case "e":
+		if m.exporting || len(m.app.GetState().ConversationLog) == 0 {
+			return m, nil
+		}
+		m.exporting = true
+		m.exportStatus = "Exporting audio..."
+		return m, ExportAudioCmd(m.app)
+	}
+	return m, nil
+}
+
+// renderHistory renders the conversation history view
+func (m *Model) renderHistory() string {
+	title := titleStyle.Render("Conversation History")
+
+	filter := "Showing all entries"
+	if m.historyFilter != "" {
+		filter = "Showing entries tagged " + m.historyFilter
+	}
+
+	help := helpStyle.Render("↑/↓ to select, PgUp/PgDn to scroll, 'x' to exclude from context, 't' to filter by tag, 'c' to copy the reply, 'e' to export as audio, Esc to go back")
+
+	content, start, end := m.formatConversationHistory()
+	m.historyView.setContent(content)
+	if m.historyToCursor {
+		m.historyView.ensureVisible(start, end)
+		m.historyToCursor = false
+	}
+	if hint := m.historyView.scrollHint(); hint != "" {
+		filter += " • " + hint
+	}
+	parts := []string{title, statusStyle.Render(filter), m.historyView.view()}
+	if m.exportStatus != "" {
+		parts = append(parts, processingStyle.Render(m.exportStatus))
+	}
+	if m.copyStatus != "" {
+		parts = append(parts, statusStyle.Render(m.copyStatus))
+	}
+	parts = append(parts, help)
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// handleTagInputKeys handles editing the tags applied to new turns
+func (m *Model) handleTagInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.uiState = Conversation
+		return m, nil
+	case "enter":
+		var tags []string
+		for _, tag := range strings.Split(m.tagInput, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		m.app.engine.SetTags(tags)
+		m.uiState = Conversation
+		return m, nil
+	case "backspace":
+		if len(m.tagInput) > 0 {
+			m.tagInput = m.tagInput[:len(m.tagInput)-1]
+		}
+		return m, nil
+	default:
+		if len(msg.String()) == 1 {
+			m.tagInput += msg.String()
+		}
+		return m, nil
+	}
+}
+
+// handleTopicInputKeys handles editing the default conversation topic, which also
+// becomes the topic of the current conversation
+func (m *Model) handleTopicInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.uiState = Settings
+		return m, nil
+	case "enter":
+		topic := strings.TrimSpace(m.topicInput)
+		if topic == "" {
+			topic = "general"
+		}
+		m.app.config.Topic = topic
+		m.app.engine.SetTopic(topic)
+		if err := m.app.config.Save(); err != nil {
+			m.error = "Failed to save settings: " + err.Error()
+		}
+		m.uiState = Settings
+		return m, nil
+	case "backspace":
+		if len(m.topicInput) > 0 {
+			m.topicInput = m.topicInput[:len(m.topicInput)-1]
+		}
+		return m, nil
+	default:
+		if len(msg.String()) == 1 {
+			m.topicInput += msg.String()
+		}
+		return m, nil
+	}
+}
+
+// renderTopicInput renders the topic editor
+func (m *Model) renderTopicInput() string {
+	title := titleStyle.Render("Conversation Topic")
+	inputField := inputStyle.Render(m.topicInput + "█")
+	help := helpStyle.Render("What your explanations will be about. Enter to save, empty for general, Esc to cancel.")
+	return lipgloss.JoinVertical(lipgloss.Center, title, "", inputField, "", help)
+}
+
+// renderTagInput renders the tag editor
+func (m *Model) renderTagInput() string {
+	title := titleStyle.Render("Tag Turns")
+	inputField := inputStyle.Render(m.tagInput + "█")
+	help := helpStyle.Render("Comma separated tags applied to the following turns. Enter to apply, empty to clear, Esc to cancel.")
+	return lipgloss.JoinVertical(lipgloss.Center, title, "", inputField, "", help)
+}
+
+// startNewPassphrase asks for the passphrase to encrypt settings with, then returns to back
+func (m *Model) startNewPassphrase(back UIState) {
+	m.passphraseNew = true
+	m.passphraseBack = back
+	m.passphraseInput = ""
+	m.passphraseError = ""
+	m.uiState = Passphrase
+}
+
+// handlePassphraseKeys handles entering the passphrase of encrypted settings
+func (m *Model) handlePassphraseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		if !m.passphraseNew {
+			// Nothing can be used or saved without the settings, so leave them untouched
+			return m, tea.Quit
+		}
+		// Without a passphrase the settings stay unencrypted
+		m.app.config.EncryptSettings = false
+		m.uiState = m.passphraseBack
+		return m, nil
+	case "backspace":
+		if runes := []rune(m.passphraseInput); len(runes) > 0 {
+			m.passphraseInput = string(runes[:len(runes)-1])
+		}
+		return m, nil
+	case "enter":
+		if m.passphraseInput == "" {
+			m.passphraseError = "The passphrase can't be empty"
+			return m, nil
+		}
+		passphrase := m.passphraseInput
+		m.passphraseInput = ""
+		if m.passphraseNew {
+			m.app.config.SetPassphrase(passphrase)
+			if err := m.app.config.Save(); err != nil {
+				m.passphraseError = "Failed to save encrypted settings: " + err.Error()
+				return m, nil
+			}
+			m.passphraseError = ""
+			m.passphraseNew = false
+			m.uiState = m.passphraseBack
+			return m, nil
+		}
+		if err := m.app.Unlock(passphrase); err != nil {
+			if m.app.config.Locked() {
+				m.passphraseError = err.Error()
+				return m, nil
+			}
+			// Unlocked, but the keys in the settings didn't check out
+			m.error = err.Error()
+		}
+		m.passphraseError = ""
+		m.selectedMode = int(m.app.state.CurrentMode)
+		m.selectedLevel = int(m.app.state.KnowledgeLevel)
+		m.uiState = MainMenu
+		if m.app.config.NeedsSetup() {
+			m.uiState = StartupWizard
+		}
+		return m, nil
+	default:
+		if len(msg.Runes) > 0 {
+			m.passphraseInput += string(msg.Runes)
+		}
+		return m, nil
+	}
+}
+
+// renderPassphrase renders the passphrase prompt with the input masked
+func (m *Model) renderPassphrase() string {
+	title := titleStyle.Render("Unlock Settings")
+	help := helpStyle.Render("Enter the passphrase your settings were encrypted with. Esc to quit.")
+	if m.passphraseNew {
+		title = titleStyle.Render("Choose a Passphrase")
+		help = helpStyle.Render("Settings will be encrypted with this passphrase. Esc to keep them unencrypted.")
+	}
+	input := inputStyle.Render(strings.Repeat("•", len([]rune(m.passphraseInput))) + "█")
+	parts := []string{title, "", input, ""}
+	if m.passphraseError != "" {
+		parts = append(parts, errorStyle.Render(m.passphraseError), "")
+	}
+	parts = append(parts, help)
+	return lipgloss.JoinVertical(lipgloss.Center, parts...)
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jorkle/jork/internal/config"
+	"github.com/jorkle/jork/internal/models"
+)
+
+// fakeRecorder is an audio.AudioRecorder that counts StopRecording calls
+type fakeRecorder struct {
+	recording bool
+	stops     int
+}
+
+func (r *fakeRecorder) StartRecording() error {
+	if r.recording {
+		return errors.New("recording is already in progress")
+	}
+	r.recording = true
+	return nil
+}
+
+func (r *fakeRecorder) StopRecording() (*models.AudioData, error) {
+	r.stops++
+	if !r.recording {
+		return nil, errors.New("no recording in progress")
+	}
+	r.recording = false
+	return &models.AudioData{Data: make([]float32, 16000), SampleRate: 16000, Channels: 1}, nil
+}
+
+func (r *fakeRecorder) IsRecording() bool     { return r.recording }
+func (r *fakeRecorder) CurrentLevel() float32 { return 0 }
+func (r *fakeRecorder) Close() error          { return nil }
+
+func (r *fakeRecorder) SaveToWAV(*models.AudioData, string) error { return nil }
+
+// newRecordingModel returns a model whose recording has just started on a fake recorder
+func newRecordingModel(t *testing.T) (*Model, *fakeRecorder) {
+	cfg := config.DefaultConfig()
+	cfg.ConfigDir = t.TempDir()
+	cfg.TerminalTitle = false
+	cfg.MinRecordingSeconds = 0
+	cfg.ReviewBeforeSend = false
+	recorder := &fakeRecorder{}
+	app := &App{
+		config:   cfg,
+		recorder: recorder,
+		state:    &models.AppState{CurrentMode: models.VoiceToVoice},
+	}
+	m := NewModel(app)
+	m.Update(StartRecordingCmd(app)())
+	if m.uiState != Recording {
+		t.Fatalf("uiState after starting = %v, want Recording", m.uiState)
+	}
+	return m, recorder
+}
+
+func TestRecordingStopsOnce(t *testing.T) {
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	esc := tea.KeyMsg{Type: tea.KeyEsc}
+
+	tests := []struct {
+		name   string
+		events []string
+		want   UIState
+	}{
+		{"enter", []string{"enter"}, Processing},
+		{"space", []string{"space"}, Processing},
+		{"silence", []string{"silence"}, Processing},
+		{"esc", []string{"esc"}, Conversation},
+		{"enter then space and silence", []string{"enter", "space", "silence"}, Processing},
+		{"silence then enter", []string{"silence", "enter", "space"}, Processing},
+		{"enter then esc", []string{"enter", "esc", "silence"}, Conversation},
+		{"esc then silence", []string{"esc", "silence"}, Conversation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, recorder := newRecordingModel(t)
+
+			// Commands run after all the events, as when the stop is slow to come back
+			var pending []tea.Cmd
+			for _, event := range tt.events {
+				var msg tea.Msg
+				switch event {
+				case "enter":
+					msg = enter
+				case "space":
+					msg = space
+				case "esc":
+					msg = esc
+				case "silence":
+					msg = silenceDetectedMsg{session: m.recordingID}
+				}
+				if _, cmd := m.Update(msg); cmd != nil {
+					pending = append(pending, cmd)
+				}
+			}
+			for _, cmd := range pending {
+				msg := cmd()
+				stopped, ok := msg.(RecordingStoppedMsg)
+				if !ok {
+					t.Fatalf("command returned %T, want RecordingStoppedMsg", msg)
+				}
+				if stopped.Error != nil {
+					t.Fatalf("stopping failed: %v", stopped.Error)
+				}
+				m.Update(stopped)
+			}
+
+			if recorder.stops != 1 {
+				t.Errorf("StopRecording called %d times, want 1", recorder.stops)
+			}
+			if m.uiState != tt.want {
+				t.Errorf("uiState = %v, want %v", m.uiState, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordingElapsed(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		start time.Time
+		now   time.Time
+		want  time.Duration
+	}{
+		{"not started", time.Time{}, start, 0},
+		{"just started", start, start, 0},
+		{"sub-second", start, start.Add(250 * time.Millisecond), 250 * time.Millisecond},
+		{"minutes", start, start.Add(2*time.Minute + 3*time.Second), 2*time.Minute + 3*time.Second},
+		{"clock before start", start, start.Add(-time.Second), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordingElapsed(tt.start, tt.now); got != tt.want {
+				t.Errorf("recordingElapsed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordedTimeLeavesOutPauses(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := &Model{recordingStart: start}
+	if got := m.recordedTime(start.Add(3 * time.Second)); got != 3*time.Second {
+		t.Errorf("recording: %v, want 3s", got)
+	}
+
+	// Paused at 3s, the time stays frozen however long the pause lasts
+	m.pausedAt = start.Add(3 * time.Second)
+	if got := m.recordedTime(start.Add(10 * time.Second)); got != 3*time.Second {
+		t.Errorf("paused: %v, want 3s", got)
+	}
+}
+
+func TestRecordingTicksStopWithTheRecording(t *testing.T) {
+	m, _ := newRecordingModel(t)
+	session := m.recordingID
+	if _, cmd := m.Update(recordingTickMsg{session: session, at: time.Now()}); cmd == nil {
+		t.Fatal("no next tick while recording")
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if _, cmd := m.Update(recordingTickMsg{session: session, at: time.Now()}); cmd != nil {
+		t.Error("ticks continue after the recording stopped")
+	}
+}
+
+func TestConversationKeysWaitForStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		key   tea.KeyMsg
+	}{
+		{"enter", "hello", tea.KeyMsg{Type: tea.KeyEnter}},
+		{"regenerate", "/regenerate", tea.KeyMsg{Type: tea.KeyEnter}},
+		{"voice input", "hello", tea.KeyMsg{Type: tea.KeyCtrlR}},
+		{"compare levels", "hello", tea.KeyMsg{Type: tea.KeyCtrlL}},
+		{"tag turns", "hello", tea.KeyMsg{Type: tea.KeyCtrlT}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newRecordingModel(t)
+			m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+			m.startStreamIndicator()
+			m.textInput = tt.input
+
+			if _, cmd := m.Update(tt.key); cmd != nil {
+				t.Error("the key started a command while the reply was streaming")
+			}
+			if m.uiState != Conversation {
+				t.Errorf("uiState = %v, want Conversation", m.uiState)
+			}
+			if m.textInput == "" {
+				t.Error("the input was submitted while the reply was streaming")
+			}
+		})
+	}
+}
+
+func TestHealthCheckedAppliesModels(t *testing.T) {
+	m, _ := newRecordingModel(t)
+	m.healthChecking = true
+	m.reportHealth = true
+	m.Update(HealthCheckedMsg{
+		Report: HealthReport{Services: []ServiceHealth{{Name: "Chat"}}},
+		Models: []string{"gpt-4o", "gpt-4o-mini"},
+	})
+
+	if got := m.app.config.AvailableModels; len(got) != 2 || got[0] != "gpt-4o" {
+		t.Errorf("AvailableModels = %v, want the checked list", got)
+	}
+	if m.healthChecking || m.settingsStatus != "Health check passed" {
+		t.Errorf("checking %v, status %q after the check finished", m.healthChecking, m.settingsStatus)
+	}
+}
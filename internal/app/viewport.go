@@ -0,0 +1,113 @@
+package app
+
+import "strings"
+
+// viewport shows a window of height lines onto content taller than the screen.
+// It does what bubbles/viewport would for the few views that scroll, without the dependency.
+type viewport struct {
+	lines  []string
+	height int // lines shown, 0 to show everything
+	offset int // index of the first line shown
+}
+
+// setContent replaces the content, keeping the view at the bottom if it was there
+// so that growing content stays in view
+func (v *viewport) setContent(content string) {
+	follow := v.atBottom()
+	v.lines = strings.Split(content, "\n")
+	if follow {
+		v.gotoBottom()
+	}
+	v.clamp()
+}
+
+// setHeight sets the number of lines shown
+func (v *viewport) setHeight(height int) {
+	v.height = max(height, 0)
+	v.clamp()
+}
+
+// scrollBy moves the view down n lines, or up for negative n
+func (v *viewport) scrollBy(n int) {
+	v.offset += n
+	v.clamp()
+}
+
+// pageUp scrolls up by a screen
+func (v *viewport) pageUp() {
+	v.scrollBy(-max(v.height-1, 1))
+}
+
+// pageDown scrolls down by a screen
+func (v *viewport) pageDown() {
+	v.scrollBy(max(v.height-1, 1))
+}
+
+// gotoBottom scrolls to the end of the content
+func (v *viewport) gotoBottom() {
+	v.offset = v.maxOffset()
+}
+
+// atBottom reports whether the end of the content is in view
+func (v *viewport) atBottom() bool {
+	return v.offset >= v.maxOffset()
+}
+
+// ensureVisible scrolls the least needed to show lines start up to end, or as
+// much of their beginning as fits
+func (v *viewport) ensureVisible(start, end int) {
+	if v.height == 0 {
+		return
+	}
+	if end-start > v.height || start < v.offset {
+		v.offset = start
+	} else if end > v.offset+v.height {
+		v.offset = end - v.height
+	}
+	v.clamp()
+}
+
+// view renders the lines in view
+func (v *viewport) view() string {
+	if v.height == 0 || len(v.lines) <= v.height {
+		return strings.Join(v.lines, "\n")
+	}
+	return strings.Join(v.lines[v.offset:v.offset+v.height], "\n")
+}
+
+// scrollHint describes the position in the content when not all of it is shown
+func (v *viewport) scrollHint() string {
+	if v.height == 0 || len(v.lines) <= v.height {
+		return ""
+	}
+	switch {
+	case v.offset == 0:
+		return "↓ more below"
+	case v.atBottom():
+		return "↑ more above"
+	}
+	return "↑↓ more above and below"
+}
+
+func (v *viewport) maxOffset() int {
+	if v.height == 0 {
+		return 0
+	}
+	return max(len(v.lines)-v.height, 0)
+}
+
+func (v *viewport) clamp() {
+	v.offset = min(max(v.offset, 0), v.maxOffset())
+}
+
+// lineCount returns the number of screen lines in blocks joined by newlines
+func lineCount(blocks []string) int {
+	n := len(blocks)
+	for _, block := range blocks {
+		n += strings.Count(block, "\n")
+	}
+	return n
+}
+
+// wheelLines is how far one mouse wheel step scrolls
+const wheelLines = 3
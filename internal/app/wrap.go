@@ -0,0 +1,35 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// boxChrome is the width taken by the border and padding of the response and input boxes
+const boxChrome = 4
+
+// wrapText wraps text to width cells, breaking between words where it can and hard-wrapping
+// words, such as URLs, that are longer than a line. Lines inside ``` code fences are
+// hard-wrapped only, so their indentation and spacing stay as they are. A width of zero or
+// less, as before the terminal size is known, leaves text unwrapped.
+func wrapText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			lines[i] = ansi.Hardwrap(line, width, true)
+			continue
+		}
+		if inCode {
+			lines[i] = ansi.Hardwrap(line, width, true)
+		} else {
+			lines[i] = ansi.Wrap(line, width, "")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,348 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// PlaybackBackend plays audio files through one particular player
+type PlaybackBackend interface {
+	// Play plays the file and blocks until playback finishes or Stop is called
+	Play(path string) error
+	// Stop interrupts the current playback
+	Stop() error
+	// Name identifies the backend in logs and diagnostics
+	Name() string
+	// Available reports whether the backend can be used on this system
+	Available() bool
+}
+
+// streamBackend is a PlaybackBackend that can also play audio as it arrives
+type streamBackend interface {
+	PlaybackBackend
+	// PlayStream plays audio read from r and blocks until playback finishes or Stop is called
+	PlayStream(r io.Reader) error
+}
+
+// commandBackend plays files by running an external player binary
+type commandBackend struct {
+	name  string
+	args  []string
+	mutex sync.Mutex
+	cmd   *exec.Cmd
+}
+
+// newCommandBackend creates a backend running name with args followed by the file path
+func newCommandBackend(name string, args ...string) *commandBackend {
+	return &commandBackend{name: name, args: args}
+}
+
+// streamingCommandBackend is a commandBackend whose player reads audio from standard input
+// when given "-" as the path
+type streamingCommandBackend struct {
+	commandBackend
+}
+
+// newStreamingCommandBackend is newCommandBackend for a player that can also be a streamBackend
+func newStreamingCommandBackend(name string, args ...string) *streamingCommandBackend {
+	return &streamingCommandBackend{commandBackend{name: name, args: args}}
+}
+
+// PlayStream pipes r to the player as it is read. If the player exits early, whatever is
+// left of r is not read; the caller closes r to release it.
+func (b *streamingCommandBackend) PlayStream(r io.Reader) error {
+	cmd := exec.Command(b.name, append(append([]string{}, b.args...), "-")...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", b.name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", b.name, err)
+	}
+	b.mutex.Lock()
+	b.cmd = cmd
+	b.mutex.Unlock()
+
+	defer func() {
+		b.mutex.Lock()
+		b.cmd = nil
+		b.mutex.Unlock()
+	}()
+
+	go func() {
+		// Ends with a write error once the player has exited and Wait closed the pipe
+		io.Copy(stdin, r)
+		stdin.Close()
+	}()
+	return cmd.Wait()
+}
+
+func (b *commandBackend) Name() string {
+	return b.name
+}
+
+func (b *commandBackend) Available() bool {
+	_, err := exec.LookPath(b.name)
+	return err == nil
+}
+
+func (b *commandBackend) Play(path string) error {
+	cmd := exec.Command(b.name, append(append([]string{}, b.args...), path)...)
+	b.mutex.Lock()
+	b.cmd = cmd
+	b.mutex.Unlock()
+
+	defer func() {
+		b.mutex.Lock()
+		b.cmd = nil
+		b.mutex.Unlock()
+	}()
+
+	return cmd.Run()
+}
+
+func (b *commandBackend) Stop() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.cmd == nil || b.cmd.Process == nil {
+		return nil
+	}
+	return b.cmd.Process.Kill()
+}
+
+// powerShellBackend plays files on Windows with a PowerShell script using the media classes
+// built into Windows, so there's nothing to install
+type powerShellBackend struct {
+	commandBackend
+	label  string // identifies the player, since every script runs powershell
+	script string // plays the file named by $env:JORK_AUDIO_FILE and returns when it ends
+}
+
+// newPowerShellBackend creates a backend running script, called label in diagnostics
+func newPowerShellBackend(label, script string) *powerShellBackend {
+	return &powerShellBackend{commandBackend: commandBackend{name: "powershell"}, label: label, script: script}
+}
+
+func (b *powerShellBackend) Name() string {
+	return b.label
+}
+
+func (b *powerShellBackend) Play(path string) error {
+	cmd := exec.Command(b.name, "-NoProfile", "-NonInteractive", "-Command", b.script)
+	// The path goes in the environment so it needn't be quoted for PowerShell
+	cmd.Env = append(os.Environ(), "JORK_AUDIO_FILE="+path)
+	b.mutex.Lock()
+	b.cmd = cmd
+	b.mutex.Unlock()
+
+	defer func() {
+		b.mutex.Lock()
+		b.cmd = nil
+		b.mutex.Unlock()
+	}()
+
+	return cmd.Run()
+}
+
+// windowsWAVScript plays a WAV file with System.Media.SoundPlayer
+const windowsWAVScript = `(New-Object System.Media.SoundPlayer $env:JORK_AUDIO_FILE).PlaySync()`
+
+// windowsMediaScript plays an MP3 file with the WPF MediaPlayer, waiting out its duration
+const windowsMediaScript = `Add-Type -AssemblyName PresentationCore
+$player = New-Object System.Windows.Media.MediaPlayer
+$player.Open([Uri](Resolve-Path -LiteralPath $env:JORK_AUDIO_FILE).Path)
+$player.Play()
+for ($i = 0; $i -lt 100 -and -not $player.NaturalDuration.HasTimeSpan; $i++) { Start-Sleep -Milliseconds 50 }
+if ($player.NaturalDuration.HasTimeSpan) { Start-Sleep -Milliseconds ([int]$player.NaturalDuration.TimeSpan.TotalMilliseconds) }
+$player.Close()`
+
+// platformBackends returns the players that come with the operating system for "wav" or
+// "mp3" files: afplay on macOS and PowerShell on Windows
+func platformBackends(format string) []PlaybackBackend {
+	switch runtime.GOOS {
+	case "darwin":
+		return []PlaybackBackend{newCommandBackend("afplay")}
+	case "windows":
+		if format == "wav" {
+			return []PlaybackBackend{newPowerShellBackend("powershell-soundplayer", windowsWAVScript)}
+		}
+		return []PlaybackBackend{newPowerShellBackend("powershell-mediaplayer", windowsMediaScript)}
+	}
+	return nil
+}
+
+// ffmpegConvertBackend converts files to WAV with ffmpeg and plays them with another backend
+type ffmpegConvertBackend struct {
+	player  PlaybackBackend
+	mutex   sync.Mutex
+	tempDir string // where converted files go, empty for the system temp directory
+}
+
+func (b *ffmpegConvertBackend) setTempDir(dir string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tempDir = dir
+}
+
+func (b *ffmpegConvertBackend) Name() string {
+	return "ffmpeg+" + b.player.Name()
+}
+
+func (b *ffmpegConvertBackend) Available() bool {
+	_, err := exec.LookPath("ffmpeg")
+	return err == nil && b.player.Available()
+}
+
+func (b *ffmpegConvertBackend) Play(path string) error {
+	// Create temporary WAV file
+	b.mutex.Lock()
+	dir := b.tempDir
+	b.mutex.Unlock()
+	tempWAV, err := os.CreateTemp(dir, "jork_converted_*.wav")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary WAV file: %w", err)
+	}
+	defer os.Remove(tempWAV.Name())
+	tempWAV.Close()
+
+	// Convert to WAV, keeping ffmpeg's diagnostics for the error
+	var stderr bytes.Buffer
+	convertCmd := exec.Command("ffmpeg", "-i", path, "-y", tempWAV.Name())
+	convertCmd.Stderr = &stderr
+	if err := convertCmd.Run(); err != nil {
+		return fmt.Errorf("failed to convert %s to WAV: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	// ffmpeg can exit cleanly yet write nothing usable, e.g. on codec problems
+	if err := checkWAVFile(tempWAV.Name()); err != nil {
+		return fmt.Errorf("ffmpeg produced no playable audio from %s: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return b.player.Play(tempWAV.Name())
+}
+
+func (b *ffmpegConvertBackend) Stop() error {
+	return b.player.Stop()
+}
+
+// portAudioBackend plays WAV files through PortAudio and needs no external binaries
+type portAudioBackend struct {
+	mutex sync.Mutex
+	stop  chan struct{}
+}
+
+func (b *portAudioBackend) Name() string {
+	return "portaudio"
+}
+
+func (b *portAudioBackend) Available() bool {
+	if err := portaudio.Initialize(); err != nil {
+		return false
+	}
+	defer portaudio.Terminate()
+
+	_, err := portaudio.DefaultOutputDevice()
+	return err == nil
+}
+
+func (b *portAudioBackend) Play(path string) error {
+	wav, err := readWAV(path)
+	if err != nil {
+		return err
+	}
+	if len(wav.samples) == 0 {
+		return nil
+	}
+
+	if err := portaudio.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize PortAudio: %w", err)
+	}
+	defer portaudio.Terminate()
+
+	done := make(chan struct{})
+	var once sync.Once
+	pos := 0
+	stream, err := portaudio.OpenDefaultStream(0, wav.channels, float64(wav.sampleRate), 1024, func(out []float32) {
+		n := copy(out, wav.samples[pos:])
+		pos += n
+		for i := n; i < len(out); i++ {
+			out[i] = 0
+		}
+		if pos >= len(wav.samples) {
+			once.Do(func() { close(done) })
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open output stream: %w", err)
+	}
+	defer stream.Close()
+
+	stop := make(chan struct{})
+	b.mutex.Lock()
+	b.stop = stop
+	b.mutex.Unlock()
+
+	if err := stream.Start(); err != nil {
+		return fmt.Errorf("failed to start output stream: %w", err)
+	}
+
+	select {
+	case <-done:
+	case <-stop:
+	}
+
+	b.mutex.Lock()
+	b.stop = nil
+	b.mutex.Unlock()
+
+	return stream.Stop()
+}
+
+func (b *portAudioBackend) Stop() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+	return nil
+}
+
+// defaultWAVBackends returns the WAV players in order of preference, starting with the OS's own
+func defaultWAVBackends() []PlaybackBackend {
+	return append(platformBackends("wav"),
+		newCommandBackend("aplay"),
+		newCommandBackend("paplay"),
+		newCommandBackend("ffplay", "-nodisp", "-autoexit"),
+		&portAudioBackend{},
+	)
+}
+
+// defaultMP3Backends returns the MP3 players in order of preference, starting with the OS's own
+func defaultMP3Backends() []PlaybackBackend {
+	return append(platformBackends("mp3"),
+		newStreamingCommandBackend("mpg123"),
+		newStreamingCommandBackend("ffplay", "-nodisp", "-autoexit"),
+		&ffmpegConvertBackend{player: newCommandBackend("paplay")},
+		&ffmpegConvertBackend{player: &portAudioBackend{}},
+	)
+}
+
+// defaultOggBackends returns the Ogg Opus players in order of preference
+func defaultOggBackends() []PlaybackBackend {
+	return []PlaybackBackend{
+		newStreamingCommandBackend("ffplay", "-nodisp", "-autoexit"),
+		newCommandBackend("paplay"),
+		&ffmpegConvertBackend{player: &portAudioBackend{}},
+	}
+}
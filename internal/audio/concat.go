@@ -0,0 +1,75 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ConcatMP3Files joins the MP3 files into output, in order, keeping any scratch files in
+// tempDir (the system temp directory when empty).
+// ffmpeg's concat demuxer is used when installed; otherwise the files are appended
+// byte for byte, which MP3 players handle since the format is a plain sequence of frames.
+func ConcatMP3Files(files []string, output, tempDir string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no audio files to join")
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if _, err := exec.LookPath("ffmpeg"); err == nil {
+		return concatWithFFmpeg(files, output, tempDir)
+	}
+	return concatRaw(files, output)
+}
+
+// concatWithFFmpeg joins the files without re-encoding using ffmpeg's concat demuxer
+func concatWithFFmpeg(files []string, output, tempDir string) error {
+	list, err := os.CreateTemp(tempDir, "jork_concat_*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create concat list: %w", err)
+	}
+	defer os.Remove(list.Name())
+
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			list.Close()
+			return err
+		}
+		fmt.Fprintf(list, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	list.Close()
+
+	cmd := exec.Command("ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", list.Name(), "-c", "copy", output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to join audio with ffmpeg: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// concatRaw appends the files to output one after another
+func concatRaw(files []string, output string) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer out.Close()
+
+	for _, file := range files {
+		in, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", file, err)
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			return fmt.Errorf("failed to append %s: %w", file, err)
+		}
+	}
+	return out.Close()
+}
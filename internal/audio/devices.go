@@ -0,0 +1,53 @@
+package audio
+
+import (
+	"fmt"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// DefaultDevice is the device name that selects the system default device
+const DefaultDevice = "default"
+
+// ListInputDevices returns the names of the devices that can record audio
+func ListInputDevices() ([]string, error) {
+	// PortAudio counts initializations, so this is safe alongside an open Recorder
+	if err := portaudio.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize PortAudio: %w", err)
+	}
+	defer portaudio.Terminate()
+
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audio devices: %w", err)
+	}
+	var names []string
+	for _, device := range devices {
+		if device.MaxInputChannels > 0 {
+			names = append(names, device.Name)
+		}
+	}
+	return names, nil
+}
+
+// inputDevice returns the input device called name, or the default device when name
+// is empty or DefaultDevice. found is false when a named device doesn't exist, e.g.
+// because it was unplugged, and the default device is returned instead.
+func inputDevice(name string) (device *portaudio.DeviceInfo, found bool, err error) {
+	if name != "" && name != DefaultDevice {
+		devices, err := portaudio.Devices()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list audio devices: %w", err)
+		}
+		for _, device := range devices {
+			if device.Name == name && device.MaxInputChannels > 0 {
+				return device, true, nil
+			}
+		}
+	}
+	device, err = portaudio.DefaultInputDevice()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get default input device: %w", err)
+	}
+	return device, name == "" || name == DefaultDevice, nil
+}
@@ -0,0 +1,97 @@
+package audio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// FileRecorder replays a WAV file as if it had been recorded, for reproducible runs without a microphone
+type FileRecorder struct {
+	path        string
+	mutex       sync.Mutex
+	isRecording bool
+	wav         *wavData
+}
+
+// NewFileRecorder creates a recorder whose recordings are the samples of the WAV file at path
+func NewFileRecorder(path string) (*FileRecorder, error) {
+	wav, err := readWAV(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRecorder{path: path, wav: wav}, nil
+}
+
+// StartRecording begins a recording; the file is re-read so edits to the fixture are picked up
+func (r *FileRecorder) StartRecording() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.isRecording {
+		return fmt.Errorf("recording is already in progress")
+	}
+	wav, err := readWAV(r.path)
+	if err != nil {
+		return err
+	}
+	r.wav = wav
+	r.isRecording = true
+	return nil
+}
+
+// StopRecording ends the recording and returns the file's samples
+func (r *FileRecorder) StopRecording() (*models.AudioData, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.isRecording {
+		return nil, fmt.Errorf("no recording in progress")
+	}
+	r.isRecording = false
+
+	var duration time.Duration
+	if r.wav.sampleRate > 0 {
+		duration = time.Duration(len(r.wav.samples)/r.wav.channels) * time.Second / time.Duration(r.wav.sampleRate)
+	}
+	audioData := &models.AudioData{
+		Data:       make([]float32, len(r.wav.samples)),
+		SampleRate: r.wav.sampleRate,
+		Channels:   r.wav.channels,
+		Duration:   duration,
+	}
+	copy(audioData.Data, r.wav.samples)
+	return audioData, nil
+}
+
+// IsRecording returns true if recording is in progress
+func (r *FileRecorder) IsRecording() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.isRecording
+}
+
+// CurrentLevel returns the RMS amplitude of the file, as nothing is captured live
+func (r *FileRecorder) CurrentLevel() float32 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.isRecording {
+		return 0
+	}
+	return rmsLevel(r.wav.samples)
+}
+
+// SaveToWAV saves audio data to a WAV file, with the source file's channel count unless the data has its own
+func (r *FileRecorder) SaveToWAV(audioData *models.AudioData, filename string) error {
+	return writeWAV(audioData, channelCount(audioData, r.wav.channels), filename)
+}
+
+// Close ends any recording in progress
+func (r *FileRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.isRecording = false
+	return nil
+}
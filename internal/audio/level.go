@@ -0,0 +1,25 @@
+package audio
+
+import "math"
+
+// rmsLevel returns the root mean square amplitude of samples, from 0 for silence to 1 at full scale
+func rmsLevel(samples []float32) float32 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return float32(math.Sqrt(sum / float64(len(samples))))
+}
+
+// LevelFraction maps an amplitude to the fraction of a meter to fill, on a decibel
+// scale from -60 dBFS (empty) to 0 dBFS (full) so quiet speech still registers
+func LevelFraction(level float32) float64 {
+	if level <= 0 {
+		return 0
+	}
+	db := 20 * math.Log10(float64(level))
+	return math.Min(math.Max((db+60)/60, 0), 1)
+}
@@ -3,8 +3,8 @@ package audio
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
-	"os/exec"
 	"sync"
 	"time"
 
@@ -13,171 +13,156 @@ import (
 
 // Player handles audio playback functionality
 type Player struct {
-	isPlaying  bool
-	mutex      sync.RWMutex
-	currentCmd *exec.Cmd
+	isPlaying   bool
+	mutex       sync.RWMutex
+	wavBackends []PlaybackBackend
+	mp3Backends []PlaybackBackend
+	oggBackends []PlaybackBackend
+	current     PlaybackBackend
+	playID      int    // incremented per playback so one that was stopped can't clear the next
+	tempDir     string // where audio is saved for playback, empty for the system temp directory
 }
 
-// NewPlayer creates a new audio player
+// NewPlayer creates a new audio player using the default backends
 func NewPlayer() *Player {
+	player := NewPlayerWithBackends(defaultWAVBackends(), defaultMP3Backends())
+	player.oggBackends = defaultOggBackends()
+	return player
+}
+
+// NewPlayerWithBackends creates a player that tries the given backends in order
+func NewPlayerWithBackends(wavBackends, mp3Backends []PlaybackBackend) *Player {
 	return &Player{
-		isPlaying: false,
+		isPlaying:   false,
+		wavBackends: wavBackends,
+		mp3Backends: mp3Backends,
 	}
 }
 
-// PlayAudioData plays audio data directly
-func (p *Player) PlayAudioData(audioData *models.AudioData) error {
+// tempFileBackend is implemented by backends that write temporary files of their own
+type tempFileBackend interface {
+	setTempDir(dir string)
+}
+
+// SetTempDir sets the directory audio is saved to when it has to be played from a file,
+// including by backends that convert it first
+func (p *Player) SetTempDir(dir string) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
-
-	if p.isPlaying {
-		return fmt.Errorf("audio is already playing")
+	p.tempDir = dir
+	for _, backends := range [][]PlaybackBackend{p.wavBackends, p.mp3Backends, p.oggBackends} {
+		for _, backend := range backends {
+			if b, ok := backend.(tempFileBackend); ok {
+				b.setTempDir(dir)
+			}
+		}
 	}
+}
 
+// createTemp creates a temporary file for playback in the player's temp directory
+func (p *Player) createTemp(pattern string) (*os.File, error) {
+	p.mutex.RLock()
+	dir := p.tempDir
+	p.mutex.RUnlock()
+	return os.CreateTemp(dir, pattern)
+}
+
+// PlayAudioData plays audio data directly
+func (p *Player) PlayAudioData(audioData *models.AudioData) error {
 	// Create a temporary WAV file
-	tempFile, err := os.CreateTemp("", "jork_audio_*.wav")
+	tempFile, err := p.createTemp("jork_audio_*.wav")
 	if err != nil {
 		return fmt.Errorf("failed to create temporary file: %w", err)
 	}
-	defer os.Remove(tempFile.Name())
 	tempFile.Close()
 
-	// Save audio data to temporary WAV file
-	recorder := &Recorder{
-		sampleRate: audioData.SampleRate,
-		channels:   1, // Assuming mono for simplicity
-	}
-	
-	if err := recorder.SaveToWAV(audioData, tempFile.Name()); err != nil {
+	// Save audio data to temporary WAV file, as mono unless it says otherwise
+	if err := writeWAV(audioData, channelCount(audioData, 1), tempFile.Name()); err != nil {
+		os.Remove(tempFile.Name())
 		return fmt.Errorf("failed to save audio data: %w", err)
 	}
 
-	// Play the WAV file
-	return p.PlayFile(tempFile.Name())
+	// Play the WAV file, removing it once playback is over
+	return p.play(tempFile.Name(), p.wavBackends, "WAV", func() { os.Remove(tempFile.Name()) })
 }
 
-// PlayFile plays an audio file using the system's default audio player
+// PlayFile plays a WAV file using the first available backend
 func (p *Player) PlayFile(filename string) error {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	if p.isPlaying {
-		return fmt.Errorf("audio is already playing")
-	}
-
-	// Check if file exists
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		return fmt.Errorf("audio file does not exist: %s", filename)
-	}
-
-	// Try different audio players based on what's available
-	var cmd *exec.Cmd
-	
-	// Try aplay (ALSA) first - common on Linux
-	if _, err := exec.LookPath("aplay"); err == nil {
-		cmd = exec.Command("aplay", filename)
-	} else if _, err := exec.LookPath("paplay"); err == nil {
-		// Try paplay (PulseAudio)
-		cmd = exec.Command("paplay", filename)
-	} else if _, err := exec.LookPath("ffplay"); err == nil {
-		// Try ffplay (FFmpeg) - more universal but requires FFmpeg
-		cmd = exec.Command("ffplay", "-nodisp", "-autoexit", filename)
-	} else {
-		return fmt.Errorf("no suitable audio player found (tried: aplay, paplay, ffplay)")
-	}
-
-	p.currentCmd = cmd
-	p.isPlaying = true
-
-	// Start the command in a goroutine
-	go func() {
-		defer func() {
-			p.mutex.Lock()
-			p.isPlaying = false
-			p.currentCmd = nil
-			p.mutex.Unlock()
-		}()
-
-		if err := cmd.Run(); err != nil {
-			// Log error but don't return it since we're in a goroutine
-			fmt.Printf("Error playing audio: %v\n", err)
-		}
-	}()
-
-	return nil
+	return p.play(filename, p.wavBackends, "WAV", nil)
 }
 
 // PlayMP3File plays an MP3 file (for OpenAI TTS output)
 func (p *Player) PlayMP3File(filename string) error {
+	return p.play(filename, p.mp3Backends, "MP3", nil)
+}
+
+// PlayOpusFile plays an Ogg Opus file (for low-quality OpenAI TTS output)
+func (p *Player) PlayOpusFile(filename string) error {
+	return p.play(filename, p.oggBackends, "Opus", nil)
+}
+
+// play starts playback of filename on the first available backend.
+// cleanup, if set, runs once playback has finished or failed to start.
+func (p *Player) play(filename string, backends []PlaybackBackend, format string, cleanup func()) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	if cleanup == nil {
+		cleanup = func() {}
+	}
+
 	if p.isPlaying {
+		cleanup()
 		return fmt.Errorf("audio is already playing")
 	}
 
 	// Check if file exists
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		cleanup()
 		return fmt.Errorf("audio file does not exist: %s", filename)
 	}
 
-	// Try different MP3 players
-	var cmd *exec.Cmd
-	
-	if _, err := exec.LookPath("mpg123"); err == nil {
-		cmd = exec.Command("mpg123", filename)
-	} else if _, err := exec.LookPath("ffplay"); err == nil {
-		cmd = exec.Command("ffplay", "-nodisp", "-autoexit", filename)
-	} else if _, err := exec.LookPath("paplay"); err == nil {
-		// Convert MP3 to WAV using ffmpeg and play with paplay
-		return p.playMP3WithFFmpeg(filename)
-	} else {
-		return fmt.Errorf("no suitable MP3 player found (tried: mpg123, ffplay, paplay+ffmpeg)")
+	backend := firstAvailable(backends)
+	if backend == nil {
+		cleanup()
+		return fmt.Errorf("no suitable %s player found (tried: %s)", format, backendNames(backends))
 	}
 
-	p.currentCmd = cmd
-	p.isPlaying = true
+	log.Printf("Playing %s via %s", format, backend.Name())
+	id := p.startPlayback(backend)
 
-	// Start the command in a goroutine
+	// Play in a goroutine; backends block until playback ends
 	go func() {
 		defer func() {
-			p.mutex.Lock()
-			p.isPlaying = false
-			p.currentCmd = nil
-			p.mutex.Unlock()
+			p.finishPlayback(id)
+			cleanup()
 		}()
 
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Error playing MP3: %v\n", err)
+		if err := backend.Play(filename); err != nil {
+			// Log error but don't return it since we're in a goroutine
+			log.Printf("Error playing %s via %s: %v", format, backend.Name(), err)
 		}
 	}()
 
 	return nil
 }
 
-// playMP3WithFFmpeg converts MP3 to WAV and plays it
-func (p *Player) playMP3WithFFmpeg(filename string) error {
-	// Check if ffmpeg is available
-	if _, err := exec.LookPath("ffmpeg"); err != nil {
-		return fmt.Errorf("ffmpeg not found, cannot convert MP3")
-	}
-
-	// Create temporary WAV file
-	tempWAV, err := os.CreateTemp("", "jork_converted_*.wav")
-	if err != nil {
-		return fmt.Errorf("failed to create temporary WAV file: %w", err)
-	}
-	defer os.Remove(tempWAV.Name())
-	tempWAV.Close()
+// startPlayback marks backend as playing and returns the playback's ID; p.mutex must be held
+func (p *Player) startPlayback(backend PlaybackBackend) int {
+	p.playID++
+	p.current = backend
+	p.isPlaying = true
+	return p.playID
+}
 
-	// Convert MP3 to WAV
-	convertCmd := exec.Command("ffmpeg", "-i", filename, "-y", tempWAV.Name())
-	if err := convertCmd.Run(); err != nil {
-		return fmt.Errorf("failed to convert MP3 to WAV: %w", err)
+// finishPlayback clears the playing state when playback id ended, unless a later one has started
+func (p *Player) finishPlayback(id int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.playID == id {
+		p.isPlaying = false
+		p.current = nil
 	}
-
-	// Play the converted WAV file
-	return p.PlayFile(tempWAV.Name())
 }
 
 // StopPlayback stops the current audio playback
@@ -185,17 +170,16 @@ func (p *Player) StopPlayback() error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	if !p.isPlaying || p.currentCmd == nil {
+	if !p.isPlaying || p.current == nil {
 		return fmt.Errorf("no audio is currently playing")
 	}
 
-	// Kill the current command
-	if err := p.currentCmd.Process.Kill(); err != nil {
+	if err := p.current.Stop(); err != nil {
 		return fmt.Errorf("failed to stop playback: %w", err)
 	}
 
 	p.isPlaying = false
-	p.currentCmd = nil
+	p.current = nil
 
 	return nil
 }
@@ -214,74 +198,152 @@ func (p *Player) WaitForPlayback() {
 	}
 }
 
+// WAVBackendName returns the name of the backend PlayAudioData and PlayFile would use, or "" if none is available
+func (p *Player) WAVBackendName() string {
+	if backend := firstAvailable(p.wavBackends); backend != nil {
+		return backend.Name()
+	}
+	return ""
+}
+
 // GetSupportedFormats returns the audio formats supported by the system
 func (p *Player) GetSupportedFormats() []string {
 	formats := []string{}
-	
-	// Check for WAV support
-	if _, err := exec.LookPath("aplay"); err == nil {
-		formats = append(formats, "WAV (via aplay)")
-	}
-	if _, err := exec.LookPath("paplay"); err == nil {
-		formats = append(formats, "WAV (via paplay)")
+	for _, backend := range p.wavBackends {
+		if backend.Available() {
+			formats = append(formats, fmt.Sprintf("WAV (via %s)", backend.Name()))
+		}
 	}
-	
-	// Check for MP3 support
-	if _, err := exec.LookPath("mpg123"); err == nil {
-		formats = append(formats, "MP3 (via mpg123)")
+	for _, backend := range p.mp3Backends {
+		if backend.Available() {
+			formats = append(formats, fmt.Sprintf("MP3 (via %s)", backend.Name()))
+		}
 	}
-	
-	// Check for universal support via ffplay
-	if _, err := exec.LookPath("ffplay"); err == nil {
-		formats = append(formats, "Multiple formats (via ffplay)")
+	for _, backend := range p.oggBackends {
+		if backend.Available() {
+			formats = append(formats, fmt.Sprintf("Opus (via %s)", backend.Name()))
+		}
 	}
-	
 	return formats
 }
 
-// StreamAudioFromReader plays audio data from an io.Reader (useful for streaming)
+// StreamAudioFromReader plays mp3, opus or wav audio from reader. With a player that reads
+// standard input, playback starts as the audio arrives; otherwise it is saved to a temporary
+// file first. Like the Play methods it returns once playback has started. If reader is an
+// io.Closer it is closed when playback ends or fails to start.
 func (p *Player) StreamAudioFromReader(reader io.Reader, format string) error {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
+	backends := p.backendsFor(format)
+	if backends == nil {
+		closeReader(reader)
+		return fmt.Errorf("unsupported format: %s", format)
+	}
 
-	if p.isPlaying {
-		return fmt.Errorf("audio is already playing")
+	if backend := firstStreamable(backends); backend != nil {
+		return p.playStream(reader, backend, format)
 	}
 
 	// Create temporary file with appropriate extension
-	var tempFile *os.File
-	var err error
-	
-	switch format {
-	case "mp3":
-		tempFile, err = os.CreateTemp("", "jork_stream_*.mp3")
-	case "wav":
-		tempFile, err = os.CreateTemp("", "jork_stream_*.wav")
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
-	}
-	
+	tempFile, err := p.createTemp("jork_stream_*." + format)
 	if err != nil {
+		closeReader(reader)
 		return fmt.Errorf("failed to create temporary file: %w", err)
 	}
-	
-	defer os.Remove(tempFile.Name())
+	cleanup := func() { os.Remove(tempFile.Name()) }
 
 	// Copy data from reader to temporary file
-	if _, err := io.Copy(tempFile, reader); err != nil {
-		tempFile.Close()
+	_, err = io.Copy(tempFile, reader)
+	closeReader(reader)
+	tempFile.Close()
+	if err != nil {
+		cleanup()
 		return fmt.Errorf("failed to write audio data: %w", err)
 	}
-	tempFile.Close()
 
-	// Play the temporary file
+	// Play the temporary file, removing it once playback is over
+	return p.play(tempFile.Name(), backends, format, cleanup)
+}
+
+// playStream starts playing reader on backend, closing reader once playback ends
+func (p *Player) playStream(reader io.Reader, backend streamBackend, format string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.isPlaying {
+		closeReader(reader)
+		return fmt.Errorf("audio is already playing")
+	}
+
+	log.Printf("Streaming %s via %s", format, backend.Name())
+	id := p.startPlayback(backend)
+
+	go func() {
+		defer func() {
+			p.finishPlayback(id)
+			// Also stops the copy to a player that exited before the stream ended
+			closeReader(reader)
+		}()
+
+		if err := backend.PlayStream(reader); err != nil {
+			log.Printf("Error streaming %s via %s: %v", format, backend.Name(), err)
+		}
+	}()
+
+	return nil
+}
+
+// backendsFor returns the backends for "mp3", "opus" or "wav" audio, or nil for other formats
+func (p *Player) backendsFor(format string) []PlaybackBackend {
 	switch format {
 	case "mp3":
-		return p.PlayMP3File(tempFile.Name())
+		return p.mp3Backends
+	case "opus":
+		return p.oggBackends
 	case "wav":
-		return p.PlayFile(tempFile.Name())
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
+		return p.wavBackends
+	}
+	return nil
+}
+
+// CanPlay reports whether a backend for "mp3", "opus" or "wav" audio is available
+func (p *Player) CanPlay(format string) bool {
+	return firstAvailable(p.backendsFor(format)) != nil
+}
+
+// closeReader closes reader if it is an io.Closer
+func closeReader(reader io.Reader) {
+	if closer, ok := reader.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// firstStreamable returns the first usable backend that can play a stream, or nil
+func firstStreamable(backends []PlaybackBackend) streamBackend {
+	for _, backend := range backends {
+		if stream, ok := backend.(streamBackend); ok && backend.Available() {
+			return stream
+		}
 	}
+	return nil
 }
 
+// firstAvailable returns the first usable backend, or nil
+func firstAvailable(backends []PlaybackBackend) PlaybackBackend {
+	for _, backend := range backends {
+		if backend.Available() {
+			return backend
+		}
+	}
+	return nil
+}
+
+// backendNames lists backend names for error messages
+func backendNames(backends []PlaybackBackend) string {
+	names := ""
+	for i, backend := range backends {
+		if i > 0 {
+			names += ", "
+		}
+		names += backend.Name()
+	}
+	return names
+}
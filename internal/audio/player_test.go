@@ -0,0 +1,150 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubBackend is a PlaybackBackend whose Play blocks until Stop is called
+type stubBackend struct {
+	name      string
+	available bool
+
+	mutex  sync.Mutex
+	plays  int
+	stop   chan struct{}
+	played chan struct{} // receives once per Play that has returned
+}
+
+func newStubBackend(name string, available bool) *stubBackend {
+	return &stubBackend{name: name, available: available, played: make(chan struct{}, 8)}
+}
+
+func (b *stubBackend) Play(path string) error {
+	b.mutex.Lock()
+	b.plays++
+	stop := make(chan struct{})
+	b.stop = stop
+	b.mutex.Unlock()
+
+	<-stop
+	b.played <- struct{}{}
+	return nil
+}
+
+func (b *stubBackend) Stop() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+	return nil
+}
+
+func (b *stubBackend) Name() string    { return b.name }
+func (b *stubBackend) Available() bool { return b.available }
+
+func (b *stubBackend) playCount() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.plays
+}
+
+// waitForPlays waits until b's Play has been entered n times
+func waitForPlays(t *testing.T, b *stubBackend, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for b.playCount() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s played %d times, want %d", b.name, b.playCount(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPlayerUsesFirstAvailableBackend(t *testing.T) {
+	tests := []struct {
+		name      string
+		available []bool
+		want      int // index of the backend that plays, -1 for none
+	}{
+		{"first available", []bool{true, true}, 0},
+		{"skips unavailable", []bool{false, true, true}, 1},
+		{"none available", []bool{false, false}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stubs []*stubBackend
+			var backends []PlaybackBackend
+			for i, available := range tt.available {
+				stub := newStubBackend(string(rune('a'+i)), available)
+				stubs = append(stubs, stub)
+				backends = append(backends, stub)
+			}
+			player := NewPlayerWithBackends(backends, nil)
+			path := filepath.Join(t.TempDir(), "clip.wav")
+			if err := os.WriteFile(path, []byte("RIFF"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := player.PlayFile(path)
+			if tt.want < 0 {
+				if err == nil {
+					t.Fatal("playing without an available backend succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			waitForPlays(t, stubs[tt.want], 1)
+			if err := player.StopPlayback(); err != nil {
+				t.Fatal(err)
+			}
+			for i, stub := range stubs {
+				if i != tt.want && stub.playCount() != 0 {
+					t.Errorf("backend %s played, want only %s", stub.name, stubs[tt.want].name)
+				}
+			}
+		})
+	}
+}
+
+func TestPlayerPlaysAgainAfterStop(t *testing.T) {
+	stub := newStubBackend("stub", true)
+	player := NewPlayerWithBackends([]PlaybackBackend{stub}, nil)
+	path := filepath.Join(t.TempDir(), "clip.wav")
+	if err := os.WriteFile(path, []byte("RIFF"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := player.PlayFile(path); err != nil {
+		t.Fatal(err)
+	}
+	waitForPlays(t, stub, 1)
+	if err := player.StopPlayback(); err != nil {
+		t.Fatal(err)
+	}
+	if player.IsPlaying() {
+		t.Fatal("still playing after StopPlayback")
+	}
+
+	if err := player.PlayFile(path); err != nil {
+		t.Fatalf("playing after a stop: %v", err)
+	}
+	waitForPlays(t, stub, 2)
+	<-stub.played // the stopped playback has returned; give its goroutine time to wind up
+	for i := 0; i < 20; i++ {
+		if !player.IsPlaying() {
+			t.Fatal("the stopped playback finishing cleared the new one")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := player.StopPlayback(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,6 +3,7 @@ package audio
 import (
 	"encoding/binary"
 	"fmt"
+	"log"
 	"os"
 	"sync"
 	"time"
@@ -11,18 +12,41 @@ import (
 	"github.com/jorkle/jork/internal/models"
 )
 
+// AudioRecorder captures audio between StartRecording and StopRecording
+type AudioRecorder interface {
+	StartRecording() error
+	StopRecording() (*models.AudioData, error)
+	IsRecording() bool
+	// CurrentLevel returns the RMS amplitude of the latest captured audio, from 0 to 1
+	CurrentLevel() float32
+	// SaveToWAV writes audio data captured by this recorder to a WAV file
+	SaveToWAV(audioData *models.AudioData, filename string) error
+	Close() error
+}
+
 // Recorder handles audio recording functionality
 type Recorder struct {
 	stream     *portaudio.Stream
 	isRecording bool
+	paused      bool // captured audio is discarded until Resume
 	buffer     []float32
 	mutex      sync.Mutex
 	sampleRate int
 	channels   int
+	device     string // name of the input device, DefaultDevice or empty for the default
+	warning    string // why the configured device wasn't used for the last recording
+	level      float32 // RMS amplitude of the last buffer captured
+
+	// Silence auto-stop, see EnableVADAutoStop
+	silenceThreshold float32
+	silenceDuration  time.Duration
+	heardSpeech      bool      // the current recording has risen above the threshold
+	silentFrames     int       // frames below the threshold since speech was last heard
+	autoStop         chan bool // receives true on silence, closed when the recording stops
 }
 
-// NewRecorder creates a new audio recorder
-func NewRecorder(sampleRate, channels int) (*Recorder, error) {
+// NewRecorder creates a new audio recorder that records from the input device called device
+func NewRecorder(sampleRate, channels int, device string) (*Recorder, error) {
 	// Initialize PortAudio
 	if err := portaudio.Initialize(); err != nil {
 		return nil, fmt.Errorf("failed to initialize PortAudio: %w", err)
@@ -31,6 +55,7 @@ func NewRecorder(sampleRate, channels int) (*Recorder, error) {
 	recorder := &Recorder{
 		sampleRate: sampleRate,
 		channels:   channels,
+		device:     device,
 		buffer:     make([]float32, 0),
 	}
 
@@ -48,19 +73,28 @@ func (r *Recorder) StartRecording() error {
 
 	// Clear the buffer
 	r.buffer = r.buffer[:0]
+	r.level = 0
+	r.paused = false
+	r.heardSpeech = false
+	r.silentFrames = 0
 
-	// Get default input device
-	defaultDevice, err := portaudio.DefaultInputDevice()
+	// Use the configured input device, falling back to the default if it has gone away
+	device, found, err := inputDevice(r.device)
 	if err != nil {
-		return fmt.Errorf("failed to get default input device: %w", err)
+		return err
+	}
+	r.warning = ""
+	if !found {
+		r.warning = fmt.Sprintf("Input device %q not found, recording from the default device", r.device)
+		log.Print(r.warning)
 	}
 
 	// Create input parameters
 	inputParams := portaudio.StreamParameters{
 		Input: portaudio.StreamDeviceParameters{
-			Device:   defaultDevice,
+			Device:   device,
 			Channels: r.channels,
-			Latency:  defaultDevice.DefaultLowInputLatency,
+			Latency:  device.DefaultLowInputLatency,
 		},
 		SampleRate:      float64(r.sampleRate),
 		FramesPerBuffer: 1024,
@@ -74,10 +108,12 @@ func (r *Recorder) StartRecording() error {
 
 	r.stream = stream
 	r.isRecording = true
+	r.autoStop = make(chan bool, 1)
 
 	// Start the stream
 	if err := r.stream.Start(); err != nil {
 		r.isRecording = false
+		close(r.autoStop)
 		return fmt.Errorf("failed to start audio stream: %w", err)
 	}
 
@@ -92,6 +128,8 @@ func (r *Recorder) StopRecording() (*models.AudioData, error) {
 		return nil, fmt.Errorf("no recording in progress")
 	}
 	r.isRecording = false
+	r.paused = false
+	close(r.autoStop)
 	r.mutex.Unlock()
 
 	if err := r.stream.Stop(); err != nil {
@@ -109,6 +147,7 @@ func (r *Recorder) StopRecording() (*models.AudioData, error) {
 	audioData := &models.AudioData{
 		Data:       make([]float32, len(r.buffer)),
 		SampleRate: r.sampleRate,
+		Channels:   r.channels,
 		Duration:   duration,
 	}
 
@@ -117,6 +156,20 @@ func (r *Recorder) StopRecording() (*models.AudioData, error) {
 	return audioData, nil
 }
 
+// SetDevice selects the input device used from the next recording on
+func (r *Recorder) SetDevice(device string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.device = device
+}
+
+// Warning returns why the configured input device wasn't used for the last recording, if it wasn't
+func (r *Recorder) Warning() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.warning
+}
+
 // IsRecording returns true if recording is in progress
 func (r *Recorder) IsRecording() bool {
 	r.mutex.Lock()
@@ -129,12 +182,103 @@ func (r *Recorder) recordCallback(inputBuffer []float32) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if r.paused {
+		// The stream keeps running so resuming is instant, but nothing is kept
+		r.level = 0
+		return
+	}
+
 	// Append the input buffer to our recording buffer
 	r.buffer = append(r.buffer, inputBuffer...)
+	r.level = rmsLevel(inputBuffer)
+	r.detectSilence(len(inputBuffer) / r.channels)
+}
+
+// Pause stops keeping captured audio without closing the stream, so the recording's
+// duration only counts the time it wasn't paused
+func (r *Recorder) Pause() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.isRecording {
+		r.paused = true
+	}
+}
+
+// Resume keeps captured audio again after Pause. The silence before the pause doesn't
+// count towards auto-stop.
+func (r *Recorder) Resume() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.paused = false
+	r.silentFrames = 0
+}
+
+// IsPaused returns true if the recording is paused
+func (r *Recorder) IsPaused() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.paused
 }
 
-// SaveToWAV saves audio data to a WAV file
+// EnableVADAutoStop makes recordings signal on AutoStop once the level stays below threshold
+// for duration after speech. Silence before anything was said never counts, so there is
+// time to start talking. A zero duration turns auto-stopping off.
+func (r *Recorder) EnableVADAutoStop(silenceThreshold float32, silenceDuration time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.silenceThreshold = silenceThreshold
+	r.silenceDuration = silenceDuration
+}
+
+// AutoStop returns a channel for the current recording that receives true when silence
+// ends it, and is closed when the recording is stopped
+func (r *Recorder) AutoStop() <-chan bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.autoStop
+}
+
+// detectSilence tracks the silence after speech across frames just captured, with
+// the mutex held, and signals AutoStop once it has lasted silenceDuration
+func (r *Recorder) detectSilence(frames int) {
+	if r.silenceDuration <= 0 || !r.isRecording {
+		return
+	}
+	if r.level >= r.silenceThreshold {
+		r.heardSpeech = true
+		r.silentFrames = 0
+		return
+	}
+	if !r.heardSpeech {
+		return
+	}
+	r.silentFrames += frames
+	if time.Duration(r.silentFrames)*time.Second >= r.silenceDuration*time.Duration(r.sampleRate) {
+		select {
+		case r.autoStop <- true:
+		default: // already signalled
+		}
+	}
+}
+
+// CurrentLevel returns the RMS amplitude of the latest captured buffer, so a muted or
+// dead microphone shows up as silence while recording
+func (r *Recorder) CurrentLevel() float32 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.isRecording {
+		return 0
+	}
+	return r.level
+}
+
+// SaveToWAV saves audio data to a WAV file, with the recorder's channel count unless the data has its own
 func (r *Recorder) SaveToWAV(audioData *models.AudioData, filename string) error {
+	return writeWAV(audioData, channelCount(audioData, r.channels), filename)
+}
+
+// writeWAV saves interleaved audio data with the given channel count as a 16-bit PCM WAV file
+func writeWAV(audioData *models.AudioData, channelCount int, filename string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("failed to create WAV file: %w", err)
@@ -164,7 +308,7 @@ func (r *Recorder) SaveToWAV(audioData *models.AudioData, filename string) error
 	}
 
 	// Fill in the header values
-	channels := uint16(r.channels)
+	channels := uint16(channelCount)
 	sampleRate := uint32(audioData.SampleRate)
 	bitsPerSample := uint16(16)
 	byteRate := sampleRate * uint32(channels) * uint32(bitsPerSample) / 8
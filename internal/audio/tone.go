@@ -0,0 +1,36 @@
+package audio
+
+import (
+	"math"
+	"time"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// toneAmplitude keeps generated tones well below full scale
+const toneAmplitude = 0.3
+
+// toneFade is the ramp at each end of a tone that avoids audible clicks
+const toneFade = 10 * time.Millisecond
+
+// GenerateTone returns a mono sine wave at frequency Hz lasting duration
+func GenerateTone(frequency float64, duration time.Duration, sampleRate int) *models.AudioData {
+	n := int(duration.Seconds() * float64(sampleRate))
+	fade := int(toneFade.Seconds() * float64(sampleRate))
+	samples := make([]float32, n)
+	for i := range samples {
+		gain := 1.0
+		if i < fade {
+			gain = float64(i) / float64(fade)
+		} else if n-i < fade {
+			gain = float64(n-i) / float64(fade)
+		}
+		samples[i] = float32(toneAmplitude * gain * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+	}
+	return &models.AudioData{
+		Data:       samples,
+		SampleRate: sampleRate,
+		Channels:   1,
+		Duration:   duration,
+	}
+}
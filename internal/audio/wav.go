@@ -0,0 +1,179 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// Limits on the format of WAV files that are read, beyond which a header is taken as corrupt
+const (
+	maxWAVChannels   = 8
+	minWAVSampleRate = 1000
+	maxWAVSampleRate = 384000
+)
+
+// wavData holds decoded PCM samples from a WAV file
+type wavData struct {
+	samples    []float32
+	sampleRate int
+	channels   int
+}
+
+// checkWAVFile checks that path is a non-empty file with a RIFF/WAVE header
+func checkWAVFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open WAV file: %w", err)
+	}
+	defer file.Close()
+
+	var riff [12]byte
+	if _, err := io.ReadFull(file, riff[:]); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("WAV file is empty")
+		}
+		return fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return fmt.Errorf("not a WAV file: %s", path)
+	}
+	return nil
+}
+
+// LoadWAV reads a 16-bit PCM WAV file, such as one written by SaveToWAV, back into audio data.
+// Samples of multi-channel files stay interleaved.
+func LoadWAV(path string) (*models.AudioData, error) {
+	wav, err := readWAV(path)
+	if err != nil {
+		return nil, err
+	}
+	frames := len(wav.samples) / wav.channels
+	return &models.AudioData{
+		Data:       wav.samples,
+		SampleRate: wav.sampleRate,
+		Channels:   wav.channels,
+		Duration:   time.Duration(frames) * time.Second / time.Duration(wav.sampleRate),
+	}, nil
+}
+
+// channelCount returns the channels interleaved in audioData, or fallback if it doesn't say
+func channelCount(audioData *models.AudioData, fallback int) int {
+	if audioData.Channels > 0 {
+		return audioData.Channels
+	}
+	return fallback
+}
+
+// DownmixToMono averages the channels of audioData into a single one, as speech
+// recognition does best on one channel carrying the whole signal. Mono data is
+// returned as is.
+func DownmixToMono(audioData *models.AudioData) *models.AudioData {
+	channels := channelCount(audioData, 1)
+	if channels == 1 {
+		return audioData
+	}
+	mono := make([]float32, len(audioData.Data)/channels)
+	for i := range mono {
+		var sum float32
+		for _, sample := range audioData.Data[i*channels : (i+1)*channels] {
+			sum += sample
+		}
+		mono[i] = sum / float32(channels)
+	}
+	return &models.AudioData{
+		Data:       mono,
+		SampleRate: audioData.SampleRate,
+		Channels:   1,
+		Duration:   audioData.Duration,
+	}
+}
+
+// readWAV decodes a 16-bit PCM WAV file, skipping chunks other than fmt and data
+func readWAV(path string) (*wavData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAV file: %w", err)
+	}
+	defer file.Close()
+
+	var riff [12]byte
+	if _, err := io.ReadFull(file, riff[:]); err != nil {
+		return nil, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file: %s", path)
+	}
+
+	wav := &wavData{}
+	var bitsPerSample uint16
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(file, header[:]); err != nil {
+			return nil, fmt.Errorf("WAV file has no data chunk")
+		}
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		switch id {
+		case "fmt ":
+			chunk := make([]byte, size)
+			if _, err := io.ReadFull(file, chunk); err != nil {
+				return nil, fmt.Errorf("failed to read fmt chunk: %w", err)
+			}
+			if len(chunk) < 16 {
+				return nil, fmt.Errorf("fmt chunk too short")
+			}
+			if format := binary.LittleEndian.Uint16(chunk[0:2]); format != 1 {
+				return nil, fmt.Errorf("unsupported WAV format %d, only PCM is supported", format)
+			}
+			wav.channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			wav.sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			bitsPerSample = binary.LittleEndian.Uint16(chunk[14:16])
+			if wav.channels < 1 || wav.channels > maxWAVChannels {
+				return nil, fmt.Errorf("unsupported WAV channel count %d", wav.channels)
+			}
+			if wav.sampleRate < minWAVSampleRate || wav.sampleRate > maxWAVSampleRate {
+				return nil, fmt.Errorf("unsupported WAV sample rate %d Hz", wav.sampleRate)
+			}
+		case "data":
+			if wav.channels == 0 {
+				return nil, fmt.Errorf("WAV data chunk precedes fmt chunk")
+			}
+			if bitsPerSample != 16 {
+				return nil, fmt.Errorf("unsupported bits per sample %d, only 16-bit is supported", bitsPerSample)
+			}
+			// Streamed WAVs, such as TTS output, leave the size at its maximum; read what is there
+			if info, err := file.Stat(); err == nil {
+				if offset, err := file.Seek(0, io.SeekCurrent); err == nil && size > info.Size()-offset {
+					size = info.Size() - offset
+				}
+			}
+			raw := make([]byte, size)
+			n, err := io.ReadFull(file, raw)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("failed to read WAV data: %w", err)
+			}
+			// A truncated data chunk still yields the samples that are present
+			raw = raw[:n-n%2]
+			wav.samples = make([]float32, len(raw)/2)
+			for i := range wav.samples {
+				wav.samples[i] = float32(int16(binary.LittleEndian.Uint16(raw[2*i:]))) / 32767
+			}
+			return wav, nil
+		default:
+			// Skip unknown chunks such as LIST/INFO; chunks are padded to an even size
+			if _, err := file.Seek(size+size%2, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("failed to skip %q chunk: %w", id, err)
+			}
+			continue
+		}
+		if size%2 == 1 {
+			file.Seek(1, io.SeekCurrent)
+		}
+	}
+}
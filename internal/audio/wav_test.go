@@ -0,0 +1,52 @@
+package audio
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+func TestWAVRoundTripStereo(t *testing.T) {
+	const sampleRate, frames = 16000, 1600
+	data := make([]float32, 0, frames*2)
+	for i := 0; i < frames; i++ {
+		// Different signals on each channel, so swapped or merged channels show up
+		left := float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+		right := float32(-0.25 + 0.5*float64(i)/frames)
+		data = append(data, left, right)
+	}
+	data[0], data[1] = 1, -1 // full scale
+
+	path := filepath.Join(t.TempDir(), "stereo.wav")
+	in := &models.AudioData{Data: data, SampleRate: sampleRate, Channels: 2}
+	if err := writeWAV(in, 2, path); err != nil {
+		t.Fatalf("writeWAV: %v", err)
+	}
+	out, err := LoadWAV(path)
+	if err != nil {
+		t.Fatalf("LoadWAV: %v", err)
+	}
+
+	if out.Channels != 2 {
+		t.Errorf("Channels = %d, want 2", out.Channels)
+	}
+	if out.SampleRate != sampleRate {
+		t.Errorf("SampleRate = %d, want %d", out.SampleRate, sampleRate)
+	}
+	if want := 100 * time.Millisecond; out.Duration != want {
+		t.Errorf("Duration = %v, want %v", out.Duration, want)
+	}
+	if len(out.Data) != len(data) {
+		t.Fatalf("got %d samples, want %d", len(out.Data), len(data))
+	}
+	// 16-bit PCM keeps samples to within a couple of quantization steps
+	const tolerance = 2.0 / 32768
+	for i := range data {
+		if diff := math.Abs(float64(out.Data[i] - data[i])); diff > tolerance {
+			t.Fatalf("sample %d (channel %d) = %v, want %v", i, i%2, out.Data[i], data[i])
+		}
+	}
+}
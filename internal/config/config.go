@@ -3,8 +3,13 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jorkle/jork/internal/models"
 )
@@ -15,6 +20,20 @@ type Config struct {
 	AnthropicAPIKey string
 	OpenAIAPIKey    string
 
+	// OpenAI organization and project requests are routed to, sent only when set
+	OpenAIOrg     string
+	OpenAIProject string
+
+	// OpenAIBaseURL is the root of an OpenAI-compatible API, such as a self-hosted gateway,
+	// used instead of https://api.openai.com/v1; HTTPProxy is the proxy requests go through,
+	// empty to use HTTPS_PROXY and the other proxy environment variables
+	OpenAIBaseURL string
+	HTTPProxy     string
+
+	// Files holding the API keys, e.g. mounted secrets; they take precedence over the keys above
+	AnthropicAPIKeyFile string
+	OpenAIAPIKeyFile    string
+
 	// AI Model Configuration
 	ClaudeModel       string
 	OpenAITTSModel    string
@@ -25,9 +44,86 @@ type Config struct {
 	STTTargetModel    string
 	ResponseVerbosity int
 	SpeechSpeed       int
+	Temperature       float64 // chat sampling temperature, 0 for the provider default
+	SpeakSummaryOnly  bool    // speak a short summary of long VoiceToVoice responses
+	SummaryMinLength  int     // response length in characters above which the summary is spoken
 	AvailableModels   []string
 	EncryptSettings   bool
 	OpenAISTTModel    string
+	STTTimeoutBase    int     // seconds allowed for any transcription upload
+	STTTimeoutFactor  float64 // extra seconds allowed per second of recorded audio
+	STTMaxRetries     int
+	STTLanguage       string // ISO-639-1 code of the spoken language, empty to auto-detect
+	STTPrompt         string // vocabulary or context for transcription, empty for the last reply
+
+	// ModelCacheTTLHours is how long a fetched model list is used before it is refreshed
+	ModelCacheTTLHours int
+
+	// EnableCache answers a repeated prompt with the same model, level, mode and topic from
+	// ConfigDir/cache instead of the API, for CacheTTLHours after it was first answered
+	EnableCache   bool
+	CacheTTLHours int
+
+	// MaxRetries is how many times a chat request failing with 429 or a transient 5xx is retried
+	MaxRetries int
+
+	// AudioQuality trades spoken-response quality for latency: "low" requests small opus files
+	// from tts-1 for the quickest first audio on slow links, "standard" MP3 from the TTS model above,
+	// and "high" MP3 from tts-1-hd, which sounds better but takes longer to generate
+	AudioQuality string
+
+	// Assistant Persona
+	AssistantName  string
+	AssistantNames map[models.KnowledgeLevel]string // per-level overrides of AssistantName
+	Greeting       string
+
+	// Topic tells the learner what the explanations are about, e.g. "quantum computing"
+	Topic string
+
+	// Per-level verbosity and temperature, applied when switching levels unless
+	// they were changed by hand this session
+	LevelPresets map[models.KnowledgeLevel]LevelPreset
+
+	// Per-mode default knowledge levels, applied when switching modes unless
+	// the level was chosen explicitly this session
+	ModeDefaultLevels      map[models.CommunicationMode]models.KnowledgeLevel
+	ApplyModeDefaultLevels bool
+
+	// StrictPersona checks each response for breaking the learner persona,
+	// at the cost of an extra request per turn
+	StrictPersona bool
+
+	// ModerateInput runs each input through the moderation endpoint before it is sent,
+	// at the cost of an extra request per turn
+	ModerateInput bool
+
+	// SessionSummaries keeps a "what we've covered" summary of the session, regenerated
+	// every SessionSummaryEvery turns and shown on the summary screen
+	SessionSummaries    bool
+	SessionSummaryEvery int
+
+	// DetectTopicChange offers a fresh context when an input shares almost no keywords
+	// with the recent turns
+	DetectTopicChange bool
+
+	// UnderstandingCheckEvery is the number of replies between "to check my understanding"
+	// paraphrases, 0 for clarifying questions only
+	UnderstandingCheckEvery int
+
+	// GuardUserInput frames user input so it can't easily override the persona
+	GuardUserInput bool
+
+	// ModelFallbacks are tried in order when the conversation model is unavailable
+	ModelFallbacks []string
+
+	// Speech Clean-up applied before synthesis
+	TTSStripMarkdown       bool
+	TTSSpellOutURLs        bool
+	TTSExpandAbbreviations bool
+	TTSRemoveEmoji         bool
+
+	// ExportUserVoice narrates the user's turns in conversation audio exports
+	ExportUserVoice string
 
 	// Audio Configuration
 	SampleRate   int
@@ -35,27 +131,129 @@ type Config struct {
 	InputDevice  string
 	OutputDevice string
 
+	// AudioInputFile, when set, is a WAV file replayed as every recording instead of the microphone.
+	// It comes from --audio-input-file or the environment for one run and is never saved.
+	AudioInputFile string `json:"-"`
+
+	// TestToneFrequency is the pitch in Hz of the output test tone
+	TestToneFrequency float64
+
+	// MinRecordingSeconds is the shortest recording that is sent for transcription
+	MinRecordingSeconds float64
+
+	// MaxRecordingSeconds stops a recording once it is this long, 0 for no limit
+	MaxRecordingSeconds float64
+
+	// ReviewBeforeSend offers to play back or discard each recording before it is transcribed
+	ReviewBeforeSend bool
+
+	// RecordChannels is the number of channels recorded, 1 for mono or 2 for stereo;
+	// stereo recordings are mixed down to mono for transcription
+	RecordChannels int
+
+	// SilenceStopSeconds stops a recording once this much silence follows speech, 0 to
+	// stop only on Enter; audio with an RMS amplitude below SilenceThreshold is silence
+	SilenceStopSeconds float64
+	SilenceThreshold   float32
+
 	// Application Settings
 	DefaultMode            models.CommunicationMode
 	DefaultKnowledgeLevel  models.KnowledgeLevel
 	MaxConversationHistory int
+	NoAltScreen            bool `json:"-"` // set by --no-altscreen for one run, never saved
+	NoMouse                bool // leave the mouse to the terminal, e.g. for selecting text
+	BatchConcurrency       int
+	WarmupOnStart          bool
+
+	// AutoplayDelaySeconds waits before playing a spoken response so it can be read first;
+	// AutoplayPrompt offers Space to play it right away and Esc to skip it, and with no
+	// delay waits for the user instead of playing automatically
+	AutoplayDelaySeconds float64
+	AutoplayPrompt       bool
+
+	// GenerateTitles asks the model to title a session after its first turn,
+	// instead of using the first words of the input
+	GenerateTitles bool
+
+	// Stateless answers every turn independently, without earlier turns as context
+	Stateless bool
+
+	// ShowTokenUsage annotates history entries with their token counts and, when
+	// rates are set, their cost in USD per million input and output tokens
+	ShowTokenUsage  bool
+	InputTokenCost  float64
+	OutputTokenCost float64
+
+	// StreamResponses shows replies to typed input as they are generated instead of all at once
+	StreamResponses bool
+
+	// StreamingCaret shows a blinking caret after a response while it is still streaming
+	StreamingCaret bool
+
+	// TerminalTitle sets the terminal title to the current level, mode and session title;
+	// turn it off for terminals that don't support the escape sequence
+	TerminalTitle bool
+
+	// MarkdownStyle renders markdown in replies shown as text: "auto" picks colors for the
+	// terminal background, "dark" or "light" force them, and "plain" shows the raw text
+	MarkdownStyle string
+
+	// SpeakErrors announces errors aloud with fixed phrases in VoiceToVoice sessions
+	SpeakErrors bool
+
+	// EchoInput shows the last user input above the response; voice turns always do
+	EchoInput bool
+
+	// Debug Logging
+	DebugLogFile    string
+	DebugSampleRate float64
+	LogFormat       string // "text" or "json"
 
 	// File Paths
-	ConfigDir    string
-	LogFile      string
-	AudioTempDir string
+	ConfigDir            string
+	LogFile              string
+	AudioTempDir         string
+	WipeAudioTempOnStart bool
+	AllowExternalTempDir bool
+
+	// MaxDiskUsageMB caps the size of everything under ConfigDir; cached audio and then exports
+	// are evicted oldest first when it is exceeded. 0 means no cap.
+	MaxDiskUsageMB int
+
+	// audioTempReady is set once AudioTempDir has been created and checked
+	audioTempReady bool
+	// forceSetup re-runs the setup wizard even when setup was completed
+	forceSetup bool
+	// passphrase encrypts the settings file when EncryptSettings is on
+	passphrase string
+	// sealed holds the encrypted settings file until it is unlocked
+	sealed *sealedSettings
+	// overrides are the settings taken from the environment for this run, by field name
+	overrides map[string]override
+}
+
+// setupMarkerFile records in ConfigDir that the setup wizard was completed
+const setupMarkerFile = ".setup_complete"
+
+// LevelPreset holds the response settings bound to a knowledge level
+type LevelPreset struct {
+	Verbosity   int
+	Temperature float64
 }
 
-// DefaultConfig returns a configuration with sensible defaults
+// DefaultConfig returns a configuration with sensible defaults and the environment's overrides
 func DefaultConfig() *Config {
+	cfg := defaults()
+	applyEnvOverrides(cfg)
+	return cfg
+}
+
+// defaults returns the built-in configuration; API keys and endpoints come from the environment
+func defaults() *Config {
 	homeDir, _ := os.UserHomeDir()
 	configDir := filepath.Join(homeDir, ".config", "jork")
 
-	return &Config{
-		// API Configuration - will be loaded from environment
-		AnthropicAPIKey: os.Getenv("ANTHROPIC_API_KEY"),
-		OpenAIAPIKey:    os.Getenv("OPENAI_API_KEY"),
-
+	cfg := &Config{
 		// AI Model Configuration
 		ClaudeModel: func() string {
 			if v := os.Getenv("OPENAI_MODEL"); v != "" {
@@ -71,9 +269,50 @@ func DefaultConfig() *Config {
 		STTTargetModel:    "whisper-1",
 		ResponseVerbosity: 2,
 		SpeechSpeed:       2,
+		AudioQuality:      "standard",
+		SpeakSummaryOnly:  false,
+		SummaryMinLength:  400,
 		AvailableModels:   []string{},
 		EncryptSettings:   false,
 		OpenAISTTModel:    "whisper-1",
+		STTTimeoutBase:    30,
+		STTTimeoutFactor:  2.0,
+		STTMaxRetries:     2,
+
+		ModelCacheTTLHours: 24,
+
+		CacheTTLHours: 24,
+
+		MaxRetries: 3,
+
+		SessionSummaryEvery: 4,
+
+		// Assistant Persona
+		AssistantName:  "AI",
+		AssistantNames: map[models.KnowledgeLevel]string{},
+		Greeting:       "",
+
+		Topic: "general",
+
+		LevelPresets: map[models.KnowledgeLevel]LevelPreset{},
+
+		// Per-mode default knowledge levels
+		ModeDefaultLevels:      map[models.CommunicationMode]models.KnowledgeLevel{},
+		ApplyModeDefaultLevels: true,
+
+		// Speech Clean-up applied before synthesis
+		TTSStripMarkdown:       true,
+		TTSSpellOutURLs:        true,
+		TTSExpandAbbreviations: true,
+		TTSRemoveEmoji:         true,
+
+		ExportUserVoice: "echo",
+
+		StreamResponses: true,
+
+		StreamingCaret: true,
+		TerminalTitle:  true,
+		MarkdownStyle:  "auto",
 
 		// Audio Configuration
 		SampleRate:   44100,
@@ -81,26 +320,57 @@ func DefaultConfig() *Config {
 		InputDevice:  "default",
 		OutputDevice: "default",
 
+		MinRecordingSeconds: 0.5,
+		TestToneFrequency:   440,
+
+		SilenceThreshold: 0.01,
+
+		RecordChannels: 1,
+
 		// Application Settings
 		DefaultMode:            models.TextToText,
 		DefaultKnowledgeLevel:  models.CoWorker,
 		MaxConversationHistory: 50,
+		BatchConcurrency:       3,
+
+		// Debug Logging - disabled unless a log file is set
+		DebugSampleRate: 1.0,
+		LogFormat:       "text",
 
 		// File Paths
 		ConfigDir:    configDir,
 		LogFile:      filepath.Join(configDir, "conversation.log"),
 		AudioTempDir: filepath.Join(configDir, "audio_temp"),
 	}
+	return cfg
 }
 
-// Load loads configuration from environment variables and validates it
+// Load reads config.json from ConfigDir over the defaults, when it exists, then applies
+// environment overrides and validates the result
 func Load() (*Config, error) {
-	config := DefaultConfig()
+	config := defaults()
 	configFile := filepath.Join(config.ConfigDir, "config.json")
 	if _, err := os.Stat(configFile); err == nil {
-		if loaded, err := LoadFromFile(configFile); err == nil {
-			config = loaded
+		loaded, err := LoadFromFile(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", configFile, err)
 		}
+		config = loaded
+	}
+	if config.Locked() {
+		// The file's settings, keys included, are only known once the TUI asks for the passphrase
+		if err := config.mergeJSONEnv(); err != nil {
+			return nil, err
+		}
+		applyEnvOverrides(config)
+		return config, nil
+	}
+	if err := config.mergeJSONEnv(); err != nil {
+		return nil, err
+	}
+	applyEnvOverrides(config)
+	if err := config.trackOverrides(config.loadKeyFiles); err != nil {
+		return nil, err
 	}
 
 	// Validate required API keys
@@ -108,16 +378,236 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required")
 	}
 
-	// Create necessary directories
-	if err := os.MkdirAll(config.ConfigDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	// Directories are created lazily, when a feature first needs them
+	return config, nil
+}
+
+// mergeJSONEnv merges the JSON object in JORK_CONFIG_JSON over the configuration, like
+// the other environment overrides for this run only. Unknown fields are rejected, as are
+// API keys, which only come from their own variables.
+func (c *Config) mergeJSONEnv() error {
+	blob := os.Getenv("JORK_CONFIG_JSON")
+	if blob == "" {
+		return nil
 	}
 
-	if err := os.MkdirAll(config.AudioTempDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create audio temp directory: %w", err)
+	openAIKey, anthropicKey := c.OpenAIAPIKey, c.AnthropicAPIKey
+	return c.trackOverrides(func() error {
+		decoder := json.NewDecoder(strings.NewReader(blob))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(c); err != nil {
+			return fmt.Errorf("invalid JORK_CONFIG_JSON: %w", err)
+		}
+		if decoder.More() {
+			return fmt.Errorf("invalid JORK_CONFIG_JSON: unexpected data after the JSON object")
+		}
+		if c.OpenAIAPIKey != openAIKey || c.AnthropicAPIKey != anthropicKey {
+			return fmt.Errorf("invalid JORK_CONFIG_JSON: API keys must be set with OPENAI_API_KEY or ANTHROPIC_API_KEY")
+		}
+		return nil
+	})
+}
+
+// loadKeyFiles replaces the API keys with the contents of their key files, when set
+func (c *Config) loadKeyFiles() error {
+	for _, key := range []struct {
+		file string
+		dest *string
+	}{
+		{c.OpenAIAPIKeyFile, &c.OpenAIAPIKey},
+		{c.AnthropicAPIKeyFile, &c.AnthropicAPIKey},
+	} {
+		if key.file == "" {
+			continue
+		}
+		data, err := os.ReadFile(key.file)
+		if err != nil {
+			return fmt.Errorf("failed to read API key file: %w", err)
+		}
+		*key.dest = strings.TrimSpace(string(data))
 	}
+	return nil
+}
 
-	return config, nil
+// AudioTempPath returns the path of name inside the audio temp directory, creating the directory on first use.
+// If the configured location isn't writable it falls back to a directory under os.TempDir and logs a warning.
+func (c *Config) AudioTempPath(name string) (string, error) {
+	if !c.audioTempReady {
+		if err := ensureWritableDir(c.AudioTempDir); err != nil {
+			fallback := filepath.Join(os.TempDir(), "jork_audio")
+			if fallbackErr := ensureWritableDir(fallback); fallbackErr != nil {
+				return "", fmt.Errorf("failed to create audio temp directory: %w", err)
+			}
+			log.Printf("Audio temp directory %s is not writable (%v), using %s", c.AudioTempDir, err, fallback)
+			c.AudioTempDir = fallback
+		}
+		c.audioTempReady = true
+	}
+	return filepath.Join(c.AudioTempDir, name), nil
+}
+
+// ensureWritableDir creates dir if needed and checks that files can be created in it
+func ensureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	probe, err := os.CreateTemp(dir, ".probe_*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// applyEnvOverrides applies settings that environment variables take precedence for.
+// They win over config.json for this run but are never written to it, so unsetting a
+// variable undoes its setting.
+func applyEnvOverrides(c *Config) {
+	c.trackOverrides(func() error {
+		applyAccountEnv(c)
+		applySettingsEnv(c)
+		return nil
+	})
+}
+
+// applyAccountEnv applies the API keys, key files and endpoints set in the environment
+func applyAccountEnv(c *Config) {
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		c.OpenAIAPIKey = key
+	}
+	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" {
+		c.AnthropicAPIKey = key
+	}
+	if path := os.Getenv("OPENAI_API_KEY_FILE"); path != "" {
+		c.OpenAIAPIKeyFile = path
+	}
+	if path := os.Getenv("ANTHROPIC_API_KEY_FILE"); path != "" {
+		c.AnthropicAPIKeyFile = path
+	}
+	if org := os.Getenv("OPENAI_ORG_ID"); org != "" {
+		c.OpenAIOrg = org
+	}
+	if project := os.Getenv("OPENAI_PROJECT_ID"); project != "" {
+		c.OpenAIProject = project
+	}
+	if baseURL := os.Getenv("OPENAI_BASE_URL"); baseURL != "" {
+		c.OpenAIBaseURL = baseURL
+	}
+	if proxy := os.Getenv("JORK_HTTP_PROXY"); proxy != "" {
+		c.HTTPProxy = proxy
+	}
+}
+
+// applySettingsEnv applies the other settings environment variables take precedence for
+func applySettingsEnv(c *Config) {
+	if os.Getenv("JORK_NO_ALTSCREEN") != "" {
+		c.NoAltScreen = true
+	}
+	if os.Getenv("JORK_NO_MOUSE") != "" {
+		c.NoMouse = true
+	}
+	if dir := os.Getenv("JORK_AUDIO_TEMP_DIR"); dir != "" {
+		c.AudioTempDir = dir
+	}
+	if os.Getenv("JORK_SETUP") != "" {
+		c.forceSetup = true
+	}
+	if path := os.Getenv("JORK_AUDIO_INPUT_FILE"); path != "" {
+		c.AudioInputFile = path
+	}
+	if os.Getenv("JORK_WIPE_AUDIO_TEMP") != "" {
+		c.WipeAudioTempOnStart = true
+	}
+	if path := os.Getenv("JORK_DEBUG_LOG"); path != "" {
+		c.DebugLogFile = path
+	}
+	if format := os.Getenv("JORK_LOG_FORMAT"); format != "" {
+		c.LogFormat = format
+	}
+	if v := os.Getenv("JORK_DEBUG_SAMPLE_RATE"); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			c.DebugSampleRate = rate
+		}
+	}
+	if v := os.Getenv("JORK_MIN_RECORDING_SECONDS"); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			c.MinRecordingSeconds = seconds
+		}
+	}
+	if v := os.Getenv("JORK_MAX_RECORDING_SECONDS"); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			c.MaxRecordingSeconds = seconds
+		}
+	}
+	if style := os.Getenv("JORK_MARKDOWN_STYLE"); style != "" {
+		c.MarkdownStyle = style
+	}
+	if os.Getenv("JORK_ENABLE_CACHE") != "" {
+		c.EnableCache = true
+	}
+	if os.Getenv("JORK_REVIEW_BEFORE_SEND") != "" {
+		c.ReviewBeforeSend = true
+	}
+	if prompt := os.Getenv("JORK_STT_PROMPT"); prompt != "" {
+		c.STTPrompt = prompt
+	}
+	if lang := os.Getenv("JORK_STT_LANGUAGE"); lang != "" {
+		c.STTLanguage = lang
+	}
+	if v := os.Getenv("JORK_RECORD_CHANNELS"); v != "" {
+		if channels, err := strconv.Atoi(v); err == nil {
+			c.RecordChannels = channels
+		}
+	}
+	if v := os.Getenv("JORK_SILENCE_STOP_SECONDS"); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			c.SilenceStopSeconds = seconds
+		}
+	}
+}
+
+// NeedsSetup reports whether the setup wizard should run: on first run, before the completion
+// marker exists, or when it was requested explicitly. The marker is used rather than config.json
+// so a config file that exists but was never completed through the wizard still triggers it.
+func (c *Config) NeedsSetup() bool {
+	if c.forceSetup {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(c.ConfigDir, setupMarkerFile))
+	return err != nil
+}
+
+// MarkSetupCompleted records that the setup wizard finished
+func (c *Config) MarkSetupCompleted() error {
+	if err := os.MkdirAll(c.ConfigDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	marker := filepath.Join(c.ConfigDir, setupMarkerFile)
+	if err := os.WriteFile(marker, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write setup marker: %w", err)
+	}
+	c.forceSetup = false
+	return nil
+}
+
+// AssistantNameFor returns the name the assistant uses at the given knowledge level
+func (c *Config) AssistantNameFor(level models.KnowledgeLevel) string {
+	if name := c.AssistantNames[level]; name != "" {
+		return name
+	}
+	if c.AssistantName != "" {
+		return c.AssistantName
+	}
+	return "AI"
+}
+
+// IsWithinConfigDir reports whether path lies inside the config directory
+func (c *Config) IsWithinConfigDir(path string) bool {
+	rel, err := filepath.Rel(c.ConfigDir, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
 // Validate checks if the configuration is valid
@@ -126,6 +616,13 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("OpenAI API key is required")
 	}
 
+	if err := validateURL(c.OpenAIBaseURL); err != nil {
+		return fmt.Errorf("invalid OpenAI base URL: %w", err)
+	}
+	if err := validateURL(c.HTTPProxy); err != nil {
+		return fmt.Errorf("invalid HTTP proxy: %w", err)
+	}
+
 	if c.SampleRate <= 0 {
 		return fmt.Errorf("sample rate must be positive")
 	}
@@ -134,26 +631,151 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("buffer size must be positive")
 	}
 
+	switch c.AudioQuality {
+	case "", "low", "standard", "high":
+	default:
+		return fmt.Errorf("audio quality must be \"low\", \"standard\" or \"high\"")
+	}
+
+	if c.TestToneFrequency <= 0 {
+		return fmt.Errorf("test tone frequency must be positive")
+	}
+
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("max retries cannot be negative")
+	}
+
+	if c.MinRecordingSeconds < 0 {
+		return fmt.Errorf("minimum recording length cannot be negative")
+	}
+	if c.MaxRecordingSeconds < 0 {
+		return fmt.Errorf("maximum recording length cannot be negative")
+	}
+	if c.MaxRecordingSeconds > 0 && c.MaxRecordingSeconds < c.MinRecordingSeconds {
+		return fmt.Errorf("maximum recording length cannot be shorter than the minimum")
+	}
+
+	switch c.MarkdownStyle {
+	case "", "auto", "dark", "light", "plain":
+	default:
+		return fmt.Errorf("markdown style must be \"auto\", \"dark\", \"light\" or \"plain\"")
+	}
+
+	if c.CacheTTLHours < 0 {
+		return fmt.Errorf("cache TTL cannot be negative")
+	}
+
+	if !isLanguageCode(c.STTLanguage) {
+		return fmt.Errorf("STT language must be a two-letter ISO-639-1 code such as \"es\", or empty to auto-detect")
+	}
+
+	if c.RecordChannels != 1 && c.RecordChannels != 2 {
+		return fmt.Errorf("record channels must be 1 (mono) or 2 (stereo)")
+	}
+
+	if c.SilenceStopSeconds < 0 || c.SilenceThreshold < 0 {
+		return fmt.Errorf("silence auto-stop settings cannot be negative")
+	}
+
+	if c.DebugSampleRate < 0 || c.DebugSampleRate > 1 {
+		return fmt.Errorf("debug sample rate must be between 0.0 and 1.0")
+	}
+
+	if c.LogFormat != "" && c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("log format must be \"text\" or \"json\"")
+	}
+
+	return nil
+}
+
+// isLanguageCode reports whether code is empty or a lowercase two-letter language code
+func isLanguageCode(code string) bool {
+	if code == "" {
+		return true
+	}
+	return len(code) == 2 && code[0] >= 'a' && code[0] <= 'z' && code[1] >= 'a' && code[1] <= 'z'
+}
+
+// validateURL checks that raw, when set, is an absolute http or https URL
+func validateURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", raw)
+	}
 	return nil
 }
 
 func (c *Config) Save() error {
+	if err := os.MkdirAll(c.ConfigDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if c.Locked() {
+		// Saving now would replace the encrypted settings with defaults
+		return ErrPassphraseRequired
+	}
 	configFile := filepath.Join(c.ConfigDir, "config.json")
-	data, err := json.MarshalIndent(c, "", "    ")
+	data, err := c.encodeForSave()
+	if err != nil {
+		return err
+	}
+	// Only the owner may read it, since the file can hold API keys
+	return writeFileAtomic(configFile, data, 0600)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so a crash mid-write never leaves a truncated file behind
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
 	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
 		return err
 	}
-	return os.WriteFile(configFile, data, 0644)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
 }
 
+// LoadFromFile reads a config file over the defaults. Encrypted files are opened with the
+// passphrase in JORK_PASSPHRASE; without one the returned config is Locked.
 func LoadFromFile(configFile string) (*Config, error) {
-	data, err := os.ReadFile(configFile)
+	passphrase := os.Getenv("JORK_PASSPHRASE")
+	data, sealed, err := readSettingsFile(configFile, passphrase)
 	if err != nil {
 		return nil, err
 	}
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	// Decode over the defaults so settings added since the file was written keep sensible values
+	cfg := defaults()
+	if sealed != nil {
+		cfg.sealed = sealed
+		return cfg, nil
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, err
 	}
-	return &cfg, nil
+	if cfg.EncryptSettings {
+		cfg.passphrase = passphrase
+	}
+	return cfg, nil
 }
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// configEnv lists the variables Load reads, so tests start from a clean environment
+var configEnv = []string{
+	"OPENAI_API_KEY", "ANTHROPIC_API_KEY", "OPENAI_API_KEY_FILE", "ANTHROPIC_API_KEY_FILE",
+	"OPENAI_ORG_ID", "OPENAI_PROJECT_ID", "OPENAI_BASE_URL", "JORK_HTTP_PROXY",
+	"JORK_CONFIG_JSON", "JORK_PASSPHRASE", "JORK_AUDIO_TEMP_DIR", "JORK_WIPE_AUDIO_TEMP",
+	"JORK_DEBUG_LOG", "JORK_ENABLE_CACHE", "JORK_REVIEW_BEFORE_SEND", "JORK_MIN_RECORDING_SECONDS",
+	"JORK_MAX_RECORDING_SECONDS", "JORK_SILENCE_STOP_SECONDS",
+}
+
+// setupHome points the config directory at a temporary home with config.json holding settings,
+// clears the configuration environment and returns the path of config.json
+func setupHome(t *testing.T, settings map[string]any) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, name := range configEnv {
+		t.Setenv(name, "")
+	}
+	configFile := filepath.Join(home, ".config", "jork", "config.json")
+	if settings != nil {
+		data, err := json.Marshal(settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(configFile, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return configFile
+}
+
+// readSaved returns the settings in config.json
+func readSaved(t *testing.T, configFile string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]any
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+func TestSaveKeepsEnvironmentAccountOutOfFile(t *testing.T) {
+	configFile := setupHome(t, map[string]any{"OpenAIAPIKey": "file-key", "OpenAIOrg": "file-org"})
+	t.Setenv("OPENAI_API_KEY", "env-key")
+	t.Setenv("ANTHROPIC_API_KEY", "env-anthropic-key")
+	t.Setenv("OPENAI_ORG_ID", "env-org")
+	t.Setenv("OPENAI_PROJECT_ID", "env-project")
+	t.Setenv("OPENAI_BASE_URL", "https://gateway.example.com/v1")
+	t.Setenv("JORK_HTTP_PROXY", "http://proxy.example.com:3128")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OpenAIAPIKey != "env-key" || cfg.OpenAIOrg != "env-org" {
+		t.Fatalf("environment didn't override the file: key %q, org %q", cfg.OpenAIAPIKey, cfg.OpenAIOrg)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := readSaved(t, configFile)
+	want := map[string]string{
+		"OpenAIAPIKey":    "file-key",
+		"AnthropicAPIKey": "",
+		"OpenAIOrg":       "file-org",
+		"OpenAIProject":   "",
+		"OpenAIBaseURL":   "",
+		"HTTPProxy":       "",
+	}
+	for field, value := range want {
+		if saved[field] != value {
+			t.Errorf("saved %s = %v, want %q", field, saved[field], value)
+		}
+	}
+	data, _ := os.ReadFile(configFile)
+	for _, secret := range []string{"env-key", "env-anthropic-key", "env-org", "env-project", "gateway", "proxy.example"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("config.json holds %q from the environment", secret)
+		}
+	}
+	if info, err := os.Stat(configFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("config.json mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// The environment still applies to the rest of the run
+	if cfg.OpenAIAPIKey != "env-key" {
+		t.Errorf("saving reset the key in use to %q", cfg.OpenAIAPIKey)
+	}
+}
+
+func TestSaveKeepsSettingsChangedThisSession(t *testing.T) {
+	configFile := setupHome(t, map[string]any{"OpenAIAPIKey": "file-key"})
+	t.Setenv("OPENAI_API_KEY", "env-key")
+	t.Setenv("OPENAI_ORG_ID", "env-org")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changed by hand in Settings, so the new values belong in the file
+	cfg.OpenAIAPIKey = "typed-key"
+	cfg.OpenAIOrg = "typed-org"
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved := readSaved(t, configFile)
+	if saved["OpenAIAPIKey"] != "typed-key" || saved["OpenAIOrg"] != "typed-org" {
+		t.Errorf("saved key %v and org %v, want the values typed this session", saved["OpenAIAPIKey"], saved["OpenAIOrg"])
+	}
+}
+
+func TestSaveLeavesKeyFileSecretOut(t *testing.T) {
+	configFile := setupHome(t, map[string]any{})
+	secret := filepath.Join(t.TempDir(), "openai_key")
+	if err := os.WriteFile(secret, []byte("mounted-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENAI_API_KEY_FILE", secret)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OpenAIAPIKey != "mounted-secret" {
+		t.Fatalf("key = %q, want the key file's contents", cfg.OpenAIAPIKey)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(configFile)
+	if strings.Contains(string(data), "mounted-secret") {
+		t.Error("config.json holds the key from the key file")
+	}
+	if strings.Contains(string(data), secret) {
+		t.Error("config.json holds the key file path from the environment")
+	}
+}
+
+func TestSaveKeepsOneRunEnvironmentSettingsOut(t *testing.T) {
+	env := map[string]string{
+		"JORK_AUDIO_TEMP_DIR":        "/tmp/jork-one-run",
+		"JORK_WIPE_AUDIO_TEMP":       "1",
+		"JORK_DEBUG_LOG":             "/tmp/jork-debug.log",
+		"JORK_ENABLE_CACHE":          "1",
+		"JORK_REVIEW_BEFORE_SEND":    "1",
+		"JORK_MIN_RECORDING_SECONDS": "2.5",
+		"JORK_MAX_RECORDING_SECONDS": "30",
+		"JORK_SILENCE_STOP_SECONDS":  "1.5",
+	}
+	configFile := setupHome(t, map[string]any{"OpenAIAPIKey": "file-key", "MaxRecordingSeconds": 120})
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	loadAndSave := func() *Config {
+		t.Helper()
+		cfg, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	cfg := loadAndSave()
+	if cfg.AudioTempDir != "/tmp/jork-one-run" || !cfg.ReviewBeforeSend || cfg.MaxRecordingSeconds != 30 {
+		t.Fatal("environment settings weren't applied")
+	}
+	defaults := defaults()
+	saved := readSaved(t, configFile)
+	want := map[string]any{
+		"AudioTempDir":         defaults.AudioTempDir,
+		"WipeAudioTempOnStart": defaults.WipeAudioTempOnStart,
+		"DebugLogFile":         defaults.DebugLogFile,
+		"EnableCache":          defaults.EnableCache,
+		"ReviewBeforeSend":     defaults.ReviewBeforeSend,
+		"MinRecordingSeconds":  defaults.MinRecordingSeconds,
+		"MaxRecordingSeconds":  float64(120),
+		"SilenceStopSeconds":   defaults.SilenceStopSeconds,
+	}
+	for field, value := range want {
+		if saved[field] != value {
+			t.Errorf("saved %s = %v, want %v", field, saved[field], value)
+		}
+	}
+
+	// Unsetting the variables undoes their settings
+	for name := range env {
+		t.Setenv(name, "")
+	}
+	cfg = loadAndSave()
+	if cfg.AudioTempDir != defaults.AudioTempDir || cfg.ReviewBeforeSend != defaults.ReviewBeforeSend || cfg.MaxRecordingSeconds != 120 {
+		t.Errorf("settings from an earlier run's environment stuck: temp dir %q, review %v, max %v",
+			cfg.AudioTempDir, cfg.ReviewBeforeSend, cfg.MaxRecordingSeconds)
+	}
+}
+
+func TestSaveKeepsConfigJSONEnvironmentOut(t *testing.T) {
+	configFile := setupHome(t, map[string]any{"OpenAIAPIKey": "file-key", "MarkdownStyle": "dark"})
+	t.Setenv("JORK_CONFIG_JSON", `{"MarkdownStyle":"light","EnableCache":true}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MarkdownStyle != "light" || !cfg.EnableCache {
+		t.Fatalf("JORK_CONFIG_JSON wasn't applied: style %q, cache %v", cfg.MarkdownStyle, cfg.EnableCache)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := readSaved(t, configFile)
+	if saved["MarkdownStyle"] != "dark" {
+		t.Errorf("saved MarkdownStyle = %v, want the file's dark", saved["MarkdownStyle"])
+	}
+	if saved["EnableCache"] != defaults().EnableCache {
+		t.Errorf("saved EnableCache = %v, want the default", saved["EnableCache"])
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrPassphraseRequired is returned when encrypted settings are read or written without a passphrase
+var ErrPassphraseRequired = errors.New("a passphrase is required for encrypted settings")
+
+// ErrWrongPassphrase is returned when encrypted settings can't be opened with the given passphrase
+var ErrWrongPassphrase = errors.New("wrong passphrase for encrypted settings")
+
+// Key derivations for encrypted settings. New files use scrypt; files sealed with PBKDF2
+// by earlier versions can still be opened.
+const (
+	kdfScrypt = "scrypt"
+	kdfPBKDF2 = "pbkdf2-sha256"
+)
+
+// scrypt work factors for newly encrypted settings
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// sealedSettings is the encrypted form of config.json: the whole serialized config sealed
+// with AES-256-GCM under a key derived from the passphrase
+type sealedSettings struct {
+	KDF        string `json:"kdf"`
+	N          int    `json:"n,omitempty"`
+	R          int    `json:"r,omitempty"`
+	P          int    `json:"p,omitempty"`
+	Iterations int    `json:"iterations,omitempty"` // PBKDF2 only
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// sealedFile is the on-disk layout of encrypted settings
+type sealedFile struct {
+	Encrypted *sealedSettings `json:"encrypted"`
+}
+
+// parseSealed returns the encrypted settings in data, or nil if data is a plain config
+func parseSealed(data []byte) *sealedSettings {
+	var file sealedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil
+	}
+	return file.Encrypted
+}
+
+// cipher returns the AEAD for passphrase under the settings' key derivation and salt
+func (s *sealedSettings) cipher(passphrase string) (cipher.AEAD, error) {
+	var key []byte
+	var err error
+	switch s.KDF {
+	case kdfScrypt:
+		key, err = scrypt.Key([]byte(passphrase), s.Salt, s.N, s.R, s.P, 32)
+	case kdfPBKDF2:
+		key, err = pbkdf2.Key(sha256.New, passphrase, s.Salt, s.Iterations, 32)
+	default:
+		return nil, fmt.Errorf("unsupported settings key derivation %q", s.KDF)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive settings key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSettings encrypts plaintext with passphrase
+func sealSettings(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	sealed := &sealedSettings{KDF: kdfScrypt, N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 16)}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := sealed.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return nil, err
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, nil)
+	return json.MarshalIndent(sealedFile{Encrypted: sealed}, "", "    ")
+}
+
+// open decrypts the settings with passphrase. GCM authenticates the ciphertext, so a wrong
+// passphrase is detected rather than producing garbage.
+func (s *sealedSettings) open(passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	aead, err := s.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted settings are corrupt")
+	}
+	plaintext, err := aead.Open(nil, s.Nonce, s.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// Locked reports whether the settings file is encrypted and hasn't been unlocked yet.
+// A locked config holds defaults and environment overrides only.
+func (c *Config) Locked() bool {
+	return c.sealed != nil
+}
+
+// NeedsPassphrase reports whether encryption is on but no passphrase is known to save with,
+// as when the option was turned on for a file that was written unencrypted
+func (c *Config) NeedsPassphrase() bool {
+	return c.EncryptSettings && c.passphrase == ""
+}
+
+// SetPassphrase sets the passphrase encrypted settings are saved with
+func (c *Config) SetPassphrase(passphrase string) {
+	c.passphrase = passphrase
+}
+
+// Unlock decrypts the settings file with passphrase and applies it, followed by the same
+// environment overrides and key files as Load
+func (c *Config) Unlock(passphrase string) error {
+	if c.sealed == nil {
+		return nil
+	}
+	plaintext, err := c.sealed.open(passphrase)
+	if err != nil {
+		return err
+	}
+	unlocked := defaults()
+	if err := json.Unmarshal(plaintext, unlocked); err != nil {
+		return fmt.Errorf("failed to decode encrypted settings: %w", err)
+	}
+	if err := unlocked.mergeJSONEnv(); err != nil {
+		return err
+	}
+	applyEnvOverrides(unlocked)
+	if err := unlocked.trackOverrides(unlocked.loadKeyFiles); err != nil {
+		return err
+	}
+	unlocked.passphrase = passphrase
+	*c = *unlocked
+	return nil
+}
+
+// encodeForSave serializes the config, sealed with the passphrase when encryption is on.
+// Settings overridden from the environment or key files keep the values they replaced.
+func (c *Config) encodeForSave() ([]byte, error) {
+	saved, err := c.withStoredValues()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(saved, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	if !c.EncryptSettings {
+		return data, nil
+	}
+	return sealSettings(data, c.passphrase)
+}
+
+// readSettingsFile reads configFile, opening it with passphrase when it is encrypted.
+// An encrypted file without a passphrase returns its sealed contents instead.
+func readSettingsFile(configFile, passphrase string) ([]byte, *sealedSettings, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealed := parseSealed(data)
+	if sealed == nil {
+		return data, nil, nil
+	}
+	if passphrase == "" {
+		return nil, sealed, nil
+	}
+	plaintext, err := sealed.open(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, nil, nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestSealedSettingsRoundTrip(t *testing.T) {
+	plaintext := []byte(`{"OpenAIAPIKey":"file-key"}`)
+	data, err := sealSettings(plaintext, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := parseSealed(data)
+	if sealed == nil {
+		t.Fatal("sealed settings weren't recognized")
+	}
+	if sealed.KDF != kdfScrypt {
+		t.Errorf("KDF = %q, want %q", sealed.KDF, kdfScrypt)
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		wantErr    error
+	}{
+		{"right passphrase", "correct horse", nil},
+		{"wrong passphrase", "battery staple", ErrWrongPassphrase},
+		{"no passphrase", "", ErrPassphraseRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sealed.open(tt.passphrase)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("open error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(got) != string(plaintext) {
+				t.Errorf("opened %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
+
+func TestOpenPBKDF2Settings(t *testing.T) {
+	plaintext := []byte(`{"OpenAIAPIKey":"file-key"}`)
+	sealed := &sealedSettings{KDF: kdfPBKDF2, Iterations: 1000, Salt: []byte("0123456789abcdef")}
+	key, err := pbkdf2.Key(sha256.New, "correct horse", sealed.Salt, sealed.Iterations, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, nil)
+
+	got, err := sealed.open("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(plaintext) {
+		t.Errorf("opened %q, want %q", got, plaintext)
+	}
+}
+
+func TestTurnOnEncryptionForPlainFile(t *testing.T) {
+	configFile := setupHome(t, map[string]any{"OpenAIAPIKey": "file-key"})
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.EncryptSettings = true
+	if !cfg.NeedsPassphrase() {
+		t.Fatal("NeedsPassphrase = false after turning encryption on for a plain file")
+	}
+	if err := cfg.Save(); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("Save without a passphrase = %v, want %v", err, ErrPassphraseRequired)
+	}
+	cfg.SetPassphrase("correct horse")
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readSaved(t, configFile)["OpenAIAPIKey"]; ok {
+		t.Error("the saved file still has the key in the clear")
+	}
+
+	locked, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !locked.Locked() {
+		t.Fatal("the encrypted file loaded unlocked without a passphrase")
+	}
+	if err := locked.Unlock("battery staple"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Unlock with the wrong passphrase = %v, want %v", err, ErrWrongPassphrase)
+	}
+	if err := locked.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if locked.Locked() || locked.OpenAIAPIKey != "file-key" {
+		t.Errorf("after Unlock: locked %v, key %q", locked.Locked(), locked.OpenAIAPIKey)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// override is a setting taken from the environment or a key file for this run. Save writes
+// stored, the value it replaced, unless the setting has since been changed from applied.
+type override struct {
+	applied json.RawMessage
+	stored  json.RawMessage
+}
+
+// fieldValues returns the JSON encoding of each saved setting, by field name
+func (c *Config) fieldValues() map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+// trackOverrides runs apply and records the settings it changed as overrides
+func (c *Config) trackOverrides(apply func() error) error {
+	before := c.fieldValues()
+	err := apply()
+	for name, value := range c.fieldValues() {
+		if bytes.Equal(value, before[name]) {
+			continue
+		}
+		stored := before[name]
+		if prev, ok := c.overrides[name]; ok && bytes.Equal(prev.applied, before[name]) {
+			// Overridden twice, e.g. by JORK_CONFIG_JSON and then a variable of its own
+			stored = prev.stored
+		}
+		if c.overrides == nil {
+			c.overrides = map[string]override{}
+		}
+		c.overrides[name] = override{applied: value, stored: stored}
+	}
+	return err
+}
+
+// withStoredValues returns a copy of the config with every override that is still in effect
+// replaced by the value it overrode, which is what belongs in config.json
+func (c *Config) withStoredValues() (*Config, error) {
+	saved := *c
+	current := c.fieldValues()
+	for name, o := range c.overrides {
+		if !bytes.Equal(current[name], o.applied) {
+			// Changed since, e.g. in Settings, so the new value is saved
+			continue
+		}
+		field := reflect.ValueOf(&saved).Elem().FieldByName(name)
+		field.Set(reflect.Zero(field.Type()))
+		if err := json.Unmarshal(o.stored, field.Addr().Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return &saved, nil
+}
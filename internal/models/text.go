@@ -0,0 +1,16 @@
+package models
+
+import "unicode/utf8"
+
+// Truncate shortens s to at most max runes, ending in an ellipsis when anything was cut.
+// It counts and cuts runes rather than bytes, so multi-byte characters are never split.
+func Truncate(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{"short", "hello", 10, "hello"},
+		{"exact length", "hello", 5, "hello"},
+		{"one over", "hello!", 5, "hell…"},
+		{"max one", "hello", 1, "…"},
+		{"max one fits", "h", 1, "h"},
+		{"max zero", "hello", 0, ""},
+		{"empty", "", 3, ""},
+		{"accented", "crème brûlée", 8, "crème b…"},
+		{"accented exact length", "café", 4, "café"},
+		{"accented cut before accent", "naïve", 3, "na…"},
+		{"emoji", "👋🌍🎉✨", 3, "👋🌍…"},
+		{"emoji exact length", "👋🌍", 2, "👋🌍"},
+		{"emoji max one", "🎉🎉", 1, "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.s, tt.max)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Truncate(%q, %d) = %q is not valid UTF-8", tt.s, tt.max, got)
+			}
+			if n := utf8.RuneCountInString(got); tt.max > 0 && n > tt.max {
+				t.Errorf("Truncate(%q, %d) is %d runes long", tt.s, tt.max, n)
+			}
+		})
+	}
+}
@@ -1,6 +1,13 @@
 package models
 
-import "time"
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // CommunicationMode represents the four different communication modes
 type CommunicationMode int
@@ -27,6 +34,61 @@ func (m CommunicationMode) String() string {
 	}
 }
 
+// UsesVoiceInput reports whether the mode records the user's speech
+func (m CommunicationMode) UsesVoiceInput() bool {
+	return m == VoiceToText || m == VoiceToVoice
+}
+
+// UsesVoiceOutput reports whether the mode speaks the AI's responses
+func (m CommunicationMode) UsesVoiceOutput() bool {
+	return m == TextToVoice || m == VoiceToVoice
+}
+
+// modeNames are the command-line names of the communication modes
+var modeNames = map[string]CommunicationMode{
+	"text-to-voice":  TextToVoice,
+	"voice-to-text":  VoiceToText,
+	"text-to-text":   TextToText,
+	"voice-to-voice": VoiceToVoice,
+}
+
+// ParseCommunicationMode returns the mode called name, such as "text-to-text"
+func ParseCommunicationMode(name string) (CommunicationMode, error) {
+	if mode, ok := modeNames[strings.ToLower(name)]; ok {
+		return mode, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q, expected text-to-text, text-to-voice, voice-to-text or voice-to-voice", name)
+}
+
+// MarshalText encodes the mode by its command-line name, so settings can say "voice-to-voice"
+func (m CommunicationMode) MarshalText() ([]byte, error) {
+	for name, mode := range modeNames {
+		if mode == m {
+			return []byte(name), nil
+		}
+	}
+	return []byte(strconv.Itoa(int(m))), nil
+}
+
+// UnmarshalText decodes a mode name, or the number settings held before modes were named
+func (m *CommunicationMode) UnmarshalText(text []byte) error {
+	if n, err := strconv.Atoi(string(text)); err == nil {
+		*m = CommunicationMode(n)
+		return nil
+	}
+	mode, err := ParseCommunicationMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// UnmarshalJSON decodes a mode name or a bare number
+func (m *CommunicationMode) UnmarshalJSON(data []byte) error {
+	return unmarshalNameJSON(data, m)
+}
+
 // KnowledgeLevel represents the AI's knowledge level setting
 type KnowledgeLevel int
 
@@ -52,6 +114,64 @@ func (k KnowledgeLevel) String() string {
 	}
 }
 
+// levelNames are the command-line names of the knowledge levels
+var levelNames = map[string]KnowledgeLevel{
+	"child":      Child,
+	"highschool": HighSchool,
+	"freshman":   FreshmanUniversity,
+	"coworker":   CoWorker,
+}
+
+// ParseKnowledgeLevel returns the level called name, such as "coworker"
+func ParseKnowledgeLevel(name string) (KnowledgeLevel, error) {
+	if level, ok := levelNames[strings.ToLower(name)]; ok {
+		return level, nil
+	}
+	return 0, fmt.Errorf("unknown level %q, expected child, highschool, freshman or coworker", name)
+}
+
+// MarshalText encodes the level by its command-line name, so settings can say "coworker"
+func (k KnowledgeLevel) MarshalText() ([]byte, error) {
+	for name, level := range levelNames {
+		if level == k {
+			return []byte(name), nil
+		}
+	}
+	return []byte(strconv.Itoa(int(k))), nil
+}
+
+// UnmarshalText decodes a level name, or the number settings held before levels were named
+func (k *KnowledgeLevel) UnmarshalText(text []byte) error {
+	if n, err := strconv.Atoi(string(text)); err == nil {
+		*k = KnowledgeLevel(n)
+		return nil
+	}
+	level, err := ParseKnowledgeLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*k = level
+	return nil
+}
+
+// UnmarshalJSON decodes a level name or a bare number
+func (k *KnowledgeLevel) UnmarshalJSON(data []byte) error {
+	return unmarshalNameJSON(data, k)
+}
+
+// unmarshalNameJSON decodes a JSON string with v's UnmarshalText. Settings and logs saved
+// before modes and levels were named hold bare numbers, which UnmarshalText accepts too.
+func unmarshalNameJSON(data []byte, v encoding.TextUnmarshaler) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return v.UnmarshalText(data)
+	}
+	return v.UnmarshalText([]byte(name))
+}
+
 func (k KnowledgeLevel) Description() string {
 	switch k {
 	case Child:
@@ -77,6 +197,12 @@ type AppState struct {
 	LastMessage     string
 	LastResponse    string
 	ConversationLog []ConversationEntry
+	LastTurnTrimmed bool // the last turn was retried with less history to fit the context window
+	LevelOverridden bool // the knowledge level was chosen explicitly this session
+	PresetOverridden bool // verbosity or temperature was changed by hand this session
+	Stateless       bool // each turn is answered without the earlier conversation as context
+	Title           string // short title of the session, set after the first turn
+	Topic           string // subject of the conversation, from Config.Topic unless overridden
 }
 
 // ConversationEntry represents a single exchange in the conversation
@@ -88,6 +214,23 @@ type ConversationEntry struct {
 	KnowledgeLevel KnowledgeLevel
 	IsVoiceInput bool
 	IsVoiceOutput bool
+	EditedFrom   time.Time // Timestamp of the entry this input was edited from, zero if new
+	Tags         []string
+	OutOfCharacter bool // a strict persona check judged the response to have broken character
+	Excluded       bool // left out of the context sent to the model, but still displayed
+	Model          string // model that produced the response
+	InputTokens    int    // prompt tokens the response used, as reported by the provider
+	OutputTokens   int    // completion tokens of the response
+}
+
+// HasTag reports whether the entry is labelled with tag
+func (e ConversationEntry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // ClaudeRequest represents a structured request to Claude API
@@ -126,6 +269,7 @@ type Message struct {
 type AudioData struct {
 	Data       []float32
 	SampleRate int
+	Channels   int // channels interleaved in Data, 0 when unknown
 	Duration   time.Duration
 }
 
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jorkle/jork/internal/models"
+)
+
+// The conversation log is mirrored to Config.LogFile as JSON lines, one entry per line.
+// New entries are appended as they are logged; edits to earlier entries rewrite the file.
+// An empty LogFile keeps the log in memory only.
+
+// LoadConversationLog replaces the conversation log with the last MaxConversationHistory
+// entries of the log file. A missing file leaves an empty log.
+func (e *Engine) LoadConversationLog() error {
+	if e.config.LogFile == "" {
+		return nil
+	}
+	e.logMutex.Lock()
+	defer e.logMutex.Unlock()
+
+	f, err := os.Open(e.config.LogFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open conversation log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []models.ConversationEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry models.ConversationEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line cut short by a crash shouldn't cost the rest of the history
+			log.Printf("Skipping unreadable line %d of %s: %v", line, e.config.LogFile, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read conversation log: %w", err)
+	}
+
+	if len(entries) > e.config.MaxConversationHistory {
+		entries = entries[len(entries)-e.config.MaxConversationHistory:]
+	}
+	e.state.ConversationLog = entries
+	return nil
+}
+
+// SaveConversationLog rewrites the log file with the current conversation log
+func (e *Engine) SaveConversationLog() error {
+	if e.config.LogFile == "" {
+		return nil
+	}
+	e.logMutex.Lock()
+	defer e.logMutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(e.config.LogFile), 0755); err != nil {
+		return fmt.Errorf("failed to create conversation log directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(e.config.LogFile), "."+filepath.Base(e.config.LogFile)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, entry := range e.state.ConversationLog {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to encode conversation entry: %w", err)
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write conversation log: %w", err)
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set conversation log permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write conversation log: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), e.config.LogFile); err != nil {
+		return fmt.Errorf("failed to replace conversation log: %w", err)
+	}
+	return nil
+}
+
+// appendToLogFile adds entry to the end of the log file. Each entry is written with a
+// single call under logMutex, so appends from concurrent turns never interleave.
+func (e *Engine) appendToLogFile(entry models.ConversationEntry) error {
+	if e.config.LogFile == "" {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation entry: %w", err)
+	}
+
+	e.logMutex.Lock()
+	defer e.logMutex.Unlock()
+	if err := os.MkdirAll(filepath.Dir(e.config.LogFile), 0755); err != nil {
+		return fmt.Errorf("failed to create conversation log directory: %w", err)
+	}
+	f, err := os.OpenFile(e.config.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open conversation log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to conversation log: %w", err)
+	}
+	return f.Close()
+}
+
+// persistLog mirrors an edit of earlier entries to the log file
+func (e *Engine) persistLog() {
+	if err := e.SaveConversationLog(); err != nil {
+		log.Printf("Error saving conversation log: %v", err)
+	}
+}
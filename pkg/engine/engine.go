@@ -0,0 +1,760 @@
+// Package engine exposes jork's conversation core without the terminal UI.
+//
+// An Engine owns the AI clients, the current mode and knowledge level, and the
+// conversation history. A minimal embedding looks like:
+//
+//	cfg, err := engine.LoadConfig()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	eng := engine.New(cfg)
+//	eng.SetKnowledgeLevel(engine.HighSchool)
+//	reply, err := eng.ProcessTextInput("Let me explain how TCP handshakes work...")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(reply)
+//
+// Voice input is handled by ProcessVoiceFile, which transcribes a WAV file
+// before processing it as text, and GenerateVoiceResponse synthesizes a reply
+// to an MP3 file in the configured audio temp directory; StreamVoiceResponse
+// returns the speech as it downloads instead.
+//
+// Entries are saved to Config.LogFile as they are logged; LoadConversationLog
+// resumes the history of an earlier session.
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jorkle/jork/internal/ai"
+	"github.com/jorkle/jork/internal/config"
+	"github.com/jorkle/jork/internal/models"
+)
+
+// Config is the engine configuration
+type Config = config.Config
+
+// CommunicationMode selects how input is received and responses are delivered
+type CommunicationMode = models.CommunicationMode
+
+// KnowledgeLevel selects the learner persona the AI role-plays
+type KnowledgeLevel = models.KnowledgeLevel
+
+// ConversationEntry is a single exchange in the conversation history
+type ConversationEntry = models.ConversationEntry
+
+// AppState is the conversation state an Engine shares with its UI
+type AppState = models.AppState
+
+// Account is the OpenAI endpoint, organization, project and proxy requests are sent with
+type Account = ai.Account
+
+// SpeechFilters selects the clean-ups applied to text before it is synthesized
+type SpeechFilters = ai.SpeechFilters
+
+// ChatClient sends conversation requests to OpenAI or Anthropic
+type ChatClient = ai.OpenAIClient
+
+// TTSClient synthesizes speech
+type TTSClient = ai.TTSClient
+
+// STTClient transcribes speech
+type STTClient = ai.STTClient
+
+const (
+	TextToVoice  = models.TextToVoice
+	VoiceToText  = models.VoiceToText
+	TextToText   = models.TextToText
+	VoiceToVoice = models.VoiceToVoice
+)
+
+const (
+	Child              = models.Child
+	HighSchool         = models.HighSchool
+	FreshmanUniversity = models.FreshmanUniversity
+	CoWorker           = models.CoWorker
+)
+
+// ErrEmptyTranscription is returned when speech-to-text produced no words
+var ErrEmptyTranscription = errors.New("couldn't hear anything, try again")
+
+// ErrCancelled is returned when the context of a turn was cancelled before it completed;
+// nothing is logged for the turn
+var ErrCancelled = errors.New("cancelled")
+
+// ErrNothingToRegenerate is returned by RegenerateLast when no turn has been logged yet
+var ErrNothingToRegenerate = errors.New("nothing to regenerate")
+
+// DefaultConfig returns a configuration with sensible defaults
+func DefaultConfig() *Config {
+	return config.DefaultConfig()
+}
+
+// LoadConfig loads the configuration from the config file and environment
+func LoadConfig() (*Config, error) {
+	return config.Load()
+}
+
+// Engine runs conversations independently of any user interface
+type Engine struct {
+	config       *config.Config
+	openaiClient *ai.OpenAIClient
+	ttsClient    *ai.TTSClient
+	sttClient    *ai.STTClient
+	state        *models.AppState
+	debugFile    *os.File
+	tags         []string   // applied to every new entry
+	logMutex     sync.Mutex // serializes writes to the conversation log file
+
+	// Providers that passed validation, so mode switches only check new ones
+	chatValidated bool
+	ttsValidated  bool
+	sttValidated  bool
+}
+
+// New creates an engine and its AI clients from cfg
+func New(cfg *Config) *Engine {
+	e := &Engine{
+		config:       cfg,
+		openaiClient: ai.NewOpenAIClient(cfg.OpenAIAPIKey, cfg.ConversationModel),
+		ttsClient:    ai.NewTTSClient(cfg.OpenAIAPIKey, cfg.OpenAITTSModel, cfg.OpenAITTSVoice),
+		sttClient:    ai.NewSTTClient(cfg.OpenAIAPIKey, cfg.OpenAISTTModel),
+		state: &models.AppState{
+			CurrentMode:     cfg.DefaultMode,
+			KnowledgeLevel:  cfg.DefaultKnowledgeLevel,
+			ConversationLog: make([]models.ConversationEntry, 0),
+			Stateless:       cfg.Stateless,
+			Topic:           cfg.Topic,
+		},
+	}
+
+	e.openaiClient.Claude = ai.NewClaudeClient(cfg.AnthropicAPIKey)
+	e.openaiClient.Fallbacks = cfg.ModelFallbacks
+	e.openaiClient.MaxRetries = cfg.MaxRetries
+	account := e.Account()
+	e.openaiClient.Account = account
+	if chatURL := account.ChatURL(); chatURL != "" {
+		e.openaiClient.BaseURL = chatURL
+	}
+	e.openaiClient.HTTPClient.Transport = account.Transport()
+	e.ttsClient.SetAccount(account)
+	e.ttsClient.SetQuality(cfg.AudioQuality)
+	e.ttsClient.SetSpeed(cfg.SpeechSpeed)
+	e.sttClient.SetAccount(account)
+	e.openaiClient.Prompt = ai.PromptOptions{
+		UnderstandingCheckEvery: cfg.UnderstandingCheckEvery,
+		GuardInput:              cfg.GuardUserInput,
+	}
+	e.applyLevelPreset(cfg.DefaultKnowledgeLevel)
+	e.sttClient.SetTimeout(time.Duration(cfg.STTTimeoutBase)*time.Second, cfg.STTTimeoutFactor)
+	e.sttClient.SetMaxRetries(cfg.STTMaxRetries)
+	e.sttClient.SetLanguage(cfg.STTLanguage)
+
+	if cfg.DebugLogFile != "" {
+		if f, err := os.OpenFile(cfg.DebugLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600); err == nil {
+			e.debugFile = f
+			e.openaiClient.Debug = ai.NewDebugLogger(f, cfg.DebugSampleRate, cfg.LogFormat)
+		} else {
+			log.Printf("Failed to open debug log %s: %v", cfg.DebugLogFile, err)
+		}
+	}
+
+	return e
+}
+
+// Account returns the OpenAI endpoint, organization and project requests are routed to
+func (e *Engine) Account() Account {
+	return Account{
+		Organization: e.config.OpenAIOrg,
+		Project:      e.config.OpenAIProject,
+		BaseURL:      e.config.OpenAIBaseURL,
+		Proxy:        e.config.HTTPProxy,
+	}
+}
+
+// Close releases resources held by the engine
+func (e *Engine) Close() error {
+	if e.debugFile != nil {
+		return e.debugFile.Close()
+	}
+	return nil
+}
+
+// Validate checks the API keys of the clients needed by the current mode
+func (e *Engine) Validate() error {
+	return e.ValidateForMode(e.state.CurrentMode)
+}
+
+// ValidateForMode checks the API keys of the clients mode needs.
+// The chat client is always checked; TTS and STT only when the mode speaks or listens.
+// Clients that already passed are not checked again.
+func (e *Engine) ValidateForMode(mode CommunicationMode) error {
+	if !e.chatValidated {
+		if err := e.openaiClient.ValidateAPIKey(); err != nil {
+			return fmt.Errorf("invalid OpenAI API key: %w", err)
+		}
+		e.chatValidated = true
+	}
+
+	if mode.UsesVoiceOutput() && !e.ttsValidated {
+		if err := e.ttsClient.ValidateAPIKey(); err != nil {
+			return fmt.Errorf("invalid OpenAI TTS API key: %w", err)
+		}
+		e.ttsValidated = true
+	}
+
+	if mode.UsesVoiceInput() && !e.sttValidated {
+		if err := e.sttClient.ValidateAPIKey(); err != nil {
+			return fmt.Errorf("invalid OpenAI STT API key: %w", err)
+		}
+		e.sttValidated = true
+	}
+
+	return nil
+}
+
+// Warmup sends tiny throwaway requests to the chat provider, and to TTS when the mode speaks,
+// so a cold backend is ready before the first real turn. Failures are logged and otherwise ignored.
+func (e *Engine) Warmup() {
+	if _, err := e.openaiClient.Complete("Reply with the single word OK.", "ping"); err != nil {
+		log.Printf("Chat warmup failed: %v", err)
+	}
+
+	if e.state.CurrentMode.UsesVoiceOutput() {
+		filename, err := e.config.AudioTempPath("warmup" + e.ttsClient.Extension())
+		if err != nil {
+			log.Printf("TTS warmup failed: %v", err)
+			return
+		}
+		if err := e.ttsClient.TextToSpeech("OK", filename); err != nil {
+			log.Printf("TTS warmup failed: %v", err)
+		}
+		os.Remove(filename)
+	}
+}
+
+// ProcessTextInput processes text input and returns AI response
+func (e *Engine) ProcessTextInput(input string) (string, error) {
+	return e.ProcessEditedTextInput(context.Background(), input, time.Time{})
+}
+
+// ProcessEditedTextInput processes input that was edited from the entry logged at editedFrom.
+// A zero editedFrom is treated as a brand-new input. Cancelling ctx abandons the turn.
+func (e *Engine) ProcessEditedTextInput(ctx context.Context, input string, editedFrom time.Time) (string, error) {
+	return e.processText(input, editedFrom, e.cacheAllowed(ctx), func(history []models.ConversationEntry) (ai.ChatResult, error) {
+		return e.openaiClient.GenerateResponseFrom(
+			ctx,
+			input,
+			e.state.KnowledgeLevel,
+			e.state.CurrentMode,
+			history,
+			e.Topic(),
+		)
+	})
+}
+
+// StreamEditedTextInput is ProcessEditedTextInput that calls onChunk with each piece of the
+// reply as it is generated. Cancelling ctx abandons the turn without logging it.
+func (e *Engine) StreamEditedTextInput(ctx context.Context, input string, editedFrom time.Time, onChunk func(string)) (string, error) {
+	return e.processText(input, editedFrom, e.cacheAllowed(ctx), func(history []models.ConversationEntry) (ai.ChatResult, error) {
+		return e.openaiClient.StreamResponseFrom(
+			ctx,
+			input,
+			e.state.KnowledgeLevel,
+			e.state.CurrentMode,
+			history,
+			e.Topic(),
+			onChunk,
+		)
+	})
+}
+
+// processText runs a text turn, getting the reply to input from the response cache when
+// useCache is set and it has one, or else from generate, and logging the entry
+func (e *Engine) processText(input string, editedFrom time.Time, useCache bool, generate func(history []models.ConversationEntry) (ai.ChatResult, error)) (string, error) {
+	e.state.IsProcessing = true
+	defer func() { e.state.IsProcessing = false }()
+
+	var result ai.ChatResult
+	cached := false
+	if useCache {
+		// A cached reply was moderated along with its input when it was first generated
+		result, cached = e.lookupResponse(input)
+		e.state.LastTurnTrimmed = false
+	}
+
+	if !cached && e.config.ModerateInput {
+		flagged, err := e.openaiClient.Moderate(input)
+		if err != nil {
+			log.Printf("Moderation check failed: %v", err)
+		} else if flagged {
+			return "", ai.ErrContentRefused
+		}
+	}
+
+	// Generate response using OpenAI
+	if !cached {
+		var err error
+		result, err = e.generateWithHistory(e.state.ConversationLog, generate)
+		if err != nil {
+			return "", err
+		}
+		if e.config.EnableCache {
+			e.storeResponse(input, result)
+		}
+	}
+	response := sanitizeResponse(result.Text)
+
+	// Log the conversation
+	entry := models.ConversationEntry{
+		Timestamp:      time.Now(),
+		UserInput:      input,
+		AIResponse:     response,
+		Mode:           e.state.CurrentMode,
+		KnowledgeLevel: e.state.KnowledgeLevel,
+		IsVoiceInput:   e.state.CurrentMode.UsesVoiceInput(),
+		IsVoiceOutput:  e.state.CurrentMode.UsesVoiceOutput(),
+		EditedFrom:     editedFrom,
+		Tags:           append([]string(nil), e.tags...),
+		Model:          result.Model,
+		InputTokens:    result.InputTokens,
+		OutputTokens:   result.OutputTokens,
+	}
+
+	if e.config.StrictPersona {
+		inCharacter, err := e.openaiClient.StaysInPersona(entry.KnowledgeLevel, response)
+		if err != nil {
+			log.Printf("Persona check failed: %v", err)
+		}
+		entry.OutOfCharacter = !inCharacter
+	}
+
+	e.appendEntry(entry)
+
+	e.state.LastMessage = input
+	e.state.LastResponse = response
+	if e.state.Title == "" {
+		e.state.Title = e.titleFor(input)
+	}
+
+	return response, nil
+}
+
+// generateWithHistory gets a reply from generate with history as context, or none in a
+// stateless session, retrying with fewer turns if the context is too long for the model
+func (e *Engine) generateWithHistory(history []models.ConversationEntry, generate func(history []models.ConversationEntry) (ai.ChatResult, error)) (ai.ChatResult, error) {
+	if e.state.Stateless {
+		// Turns are still logged for display, just not sent as context
+		history = nil
+	}
+	result, err := generate(history)
+	e.state.LastTurnTrimmed = false
+	if errors.Is(err, ai.ErrContextLengthExceeded) {
+		// Retry once with only the most recent turns
+		result, err = generate(trimHistory(history))
+		e.state.LastTurnTrimmed = err == nil
+	}
+	if errors.Is(err, context.Canceled) {
+		return ai.ChatResult{}, ErrCancelled
+	}
+	if err != nil {
+		return ai.ChatResult{}, fmt.Errorf("failed to generate response: %w", err)
+	}
+	return result, nil
+}
+
+// RegenerateLast asks again for a reply to the last logged input, with the turns before it
+// as context, and replaces that entry's response instead of logging a new turn
+func (e *Engine) RegenerateLast(ctx context.Context) (string, error) {
+	last, ok := e.LastEntry()
+	if !ok {
+		return "", ErrNothingToRegenerate
+	}
+	e.state.IsProcessing = true
+	defer func() { e.state.IsProcessing = false }()
+
+	history := e.state.ConversationLog[:len(e.state.ConversationLog)-1]
+	result, err := e.generateWithHistory(history, func(history []models.ConversationEntry) (ai.ChatResult, error) {
+		return e.openaiClient.GenerateResponseFrom(
+			ctx,
+			last.UserInput,
+			e.state.KnowledgeLevel,
+			e.state.CurrentMode,
+			history,
+			e.Topic(),
+		)
+	})
+	if err != nil {
+		return "", err
+	}
+	response := sanitizeResponse(result.Text)
+
+	outOfCharacter := false
+	if e.config.StrictPersona {
+		inCharacter, err := e.openaiClient.StaysInPersona(e.state.KnowledgeLevel, response)
+		if err != nil {
+			log.Printf("Persona check failed: %v", err)
+		}
+		outOfCharacter = !inCharacter
+	}
+
+	for i := len(e.state.ConversationLog) - 1; i >= 0; i-- {
+		entry := &e.state.ConversationLog[i]
+		if !entry.Timestamp.Equal(last.Timestamp) {
+			continue
+		}
+		entry.AIResponse = response
+		entry.KnowledgeLevel = e.state.KnowledgeLevel
+		entry.Model = result.Model
+		entry.InputTokens = result.InputTokens
+		entry.OutputTokens = result.OutputTokens
+		entry.OutOfCharacter = outOfCharacter
+		e.persistLog()
+		break
+	}
+	e.state.LastResponse = response
+	return response, nil
+}
+
+// titleFor titles a session opening with input, falling back to its first words
+func (e *Engine) titleFor(input string) string {
+	if e.config.GenerateTitles {
+		title, err := e.openaiClient.GenerateTitle(input)
+		if err == nil {
+			return title
+		}
+		log.Printf("Title generation failed: %v", err)
+	}
+	return ai.TitleFromInput(input)
+}
+
+// ProcessVoiceFile transcribes a recorded audio file and processes the transcription as text.
+// Cancelling ctx abandons the turn.
+func (e *Engine) ProcessVoiceFile(ctx context.Context, audioFilePath string) (string, error) {
+	e.state.IsProcessing = true
+	defer func() { e.state.IsProcessing = false }()
+
+	// Convert speech to text using OpenAI Whisper
+	e.sttClient.SetPrompt(e.transcriptionPrompt())
+	transcription, err := e.sttClient.SpeechToTextContext(ctx, audioFilePath)
+	if errors.Is(err, context.Canceled) {
+		return "", ErrCancelled
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+
+	// Very short or noisy clips can come back empty; don't send those to the model
+	if strings.TrimSpace(transcription) == "" {
+		return "", ErrEmptyTranscription
+	}
+
+	// Process the transcription as text
+	return e.ProcessEditedTextInput(ctx, transcription, time.Time{})
+}
+
+// speechSummaryInstruction asks for a short spoken version of a long response
+const speechSummaryInstruction = "Summarize the following text in at most two sentences. The summary will be read aloud, so use plain conversational language without formatting or symbols."
+
+// SpeechText returns the text to synthesize for response. With SpeakSummaryOnly set,
+// long VoiceToVoice responses are replaced by a short summary; the full text is unaffected elsewhere.
+// If summarizing fails the full response is spoken.
+func (e *Engine) SpeechText(response string) string {
+	if !e.config.SpeakSummaryOnly || e.state.CurrentMode != models.VoiceToVoice {
+		return response
+	}
+	if len([]rune(response)) <= e.config.SummaryMinLength {
+		return response
+	}
+	summary, err := e.openaiClient.Complete(speechSummaryInstruction, response)
+	if err != nil || strings.TrimSpace(summary) == "" {
+		return response
+	}
+	return summary
+}
+
+// SpeechFilters returns the clean-ups configured for text before synthesis
+func (e *Engine) SpeechFilters() SpeechFilters {
+	return SpeechFilters{
+		StripMarkdown:       e.config.TTSStripMarkdown,
+		SpellOutURLs:        e.config.TTSSpellOutURLs,
+		ExpandAbbreviations: e.config.TTSExpandAbbreviations,
+		RemoveEmoji:         e.config.TTSRemoveEmoji,
+	}
+}
+
+// GenerateVoiceResponse converts text response to speech and returns the audio file path
+func (e *Engine) GenerateVoiceResponse(text string) (string, error) {
+	e.state.IsProcessing = true
+	defer func() { e.state.IsProcessing = false }()
+
+	// Generate unique filename
+	filename, err := e.config.AudioTempPath(fmt.Sprintf("response_%d%s", time.Now().Unix(), e.ttsClient.Extension()))
+	if err != nil {
+		return "", err
+	}
+
+	// Clean up text that reads badly aloud; the displayed response is unaffected
+	text = e.SpeechFilters().Apply(text)
+
+	// Convert text to speech
+	if err := e.ttsClient.TextToSpeech(text, filename); err != nil {
+		return "", fmt.Errorf("failed to generate speech: %w", err)
+	}
+
+	return filename, nil
+}
+
+// StreamVoiceResponse converts text response to speech like GenerateVoiceResponse, returning
+// the audio as it downloads instead of saving it to a file. The caller must close the stream.
+func (e *Engine) StreamVoiceResponse(text string) (io.ReadCloser, error) {
+	stream, err := e.ttsClient.TextToSpeechStream(e.SpeechFilters().Apply(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate speech: %w", err)
+	}
+	return stream, nil
+}
+
+// transcriptionPrompt returns the context speech is transcribed with: Config.STTPrompt when
+// set, otherwise the last reply, so terms the user picks up from it are spelled the same way
+func (e *Engine) transcriptionPrompt() string {
+	if e.config.STTPrompt != "" {
+		return e.config.STTPrompt
+	}
+	if last, ok := e.LastEntry(); ok && !e.state.Stateless {
+		return last.AIResponse
+	}
+	return ""
+}
+
+// trimmedHistoryLen is the number of turns kept when a request overflows the context window
+const trimmedHistoryLen = 2
+
+// trimHistory drops all but the most recent turns
+func trimHistory(history []models.ConversationEntry) []models.ConversationEntry {
+	if len(history) <= trimmedHistoryLen {
+		return nil
+	}
+	return history[len(history)-trimmedHistoryLen:]
+}
+
+// escapeSequencePattern matches ANSI/VT escape sequences that could restyle or move the terminal
+var escapeSequencePattern = regexp.MustCompile(`\x1b(\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(\x07|\x1b\\)|.)`)
+
+// sanitizeResponse makes model output safe to store and render: invalid UTF-8 is replaced,
+// escape sequences are removed and control characters other than newlines and tabs are dropped
+func sanitizeResponse(response string) string {
+	response = strings.ToValidUTF8(response, "\uFFFD")
+	response = escapeSequencePattern.ReplaceAllString(response, "")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, response)
+}
+
+// appendEntry adds an entry to the log, keeping only the last MaxConversationHistory entries
+func (e *Engine) appendEntry(entry models.ConversationEntry) {
+	e.state.ConversationLog = append(e.state.ConversationLog, entry)
+
+	if len(e.state.ConversationLog) > e.config.MaxConversationHistory {
+		e.state.ConversationLog = e.state.ConversationLog[len(e.state.ConversationLog)-e.config.MaxConversationHistory:]
+		// Rewrite rather than append so the file doesn't outgrow the history it is loaded into
+		e.persistLog()
+		return
+	}
+	if err := e.appendToLogFile(entry); err != nil {
+		log.Printf("Error saving conversation log: %v", err)
+	}
+}
+
+// FindEntryByInput returns the most recent entry whose user input equals input
+func (e *Engine) FindEntryByInput(input string) (ConversationEntry, bool) {
+	for i := len(e.state.ConversationLog) - 1; i >= 0; i-- {
+		if e.state.ConversationLog[i].UserInput == input {
+			return e.state.ConversationLog[i], true
+		}
+	}
+	return ConversationEntry{}, false
+}
+
+// LastEntry returns the most recent conversation entry
+func (e *Engine) LastEntry() (ConversationEntry, bool) {
+	if len(e.state.ConversationLog) == 0 {
+		return ConversationEntry{}, false
+	}
+	return e.state.ConversationLog[len(e.state.ConversationLog)-1], true
+}
+
+// DiscardEntry removes the entry logged at timestamp, e.g. for a turn the user abandoned
+func (e *Engine) DiscardEntry(timestamp time.Time) {
+	for i := len(e.state.ConversationLog) - 1; i >= 0; i-- {
+		if e.state.ConversationLog[i].Timestamp.Equal(timestamp) {
+			e.state.ConversationLog = append(e.state.ConversationLog[:i], e.state.ConversationLog[i+1:]...)
+			e.persistLog()
+			return
+		}
+	}
+}
+
+// SetExcluded marks the entry logged at timestamp as left out of (true) or included in (false) the context
+func (e *Engine) SetExcluded(timestamp time.Time, excluded bool) {
+	for i := range e.state.ConversationLog {
+		if e.state.ConversationLog[i].Timestamp.Equal(timestamp) {
+			e.state.ConversationLog[i].Excluded = excluded
+			e.persistLog()
+			return
+		}
+	}
+}
+
+// SetTags sets the labels applied to subsequent turns
+func (e *Engine) SetTags(tags []string) {
+	e.tags = append([]string(nil), tags...)
+}
+
+// Tags returns the labels applied to subsequent turns
+func (e *Engine) Tags() []string {
+	return append([]string(nil), e.tags...)
+}
+
+// KnownTags returns every tag used in the history, in order of first use
+func (e *Engine) KnownTags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, entry := range e.state.ConversationLog {
+		for _, tag := range entry.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// SetMode changes the communication mode
+func (e *Engine) SetMode(mode CommunicationMode) {
+	e.state.CurrentMode = mode
+
+	// Apply the mode's default level unless the user picked one explicitly
+	if e.config.ApplyModeDefaultLevels && !e.state.LevelOverridden {
+		if level, ok := e.config.ModeDefaultLevels[mode]; ok {
+			e.state.KnowledgeLevel = level
+			e.applyLevelPreset(level)
+		}
+	}
+}
+
+// Mode returns the current communication mode
+func (e *Engine) Mode() CommunicationMode {
+	return e.state.CurrentMode
+}
+
+// SetKnowledgeLevel changes the knowledge level
+func (e *Engine) SetKnowledgeLevel(level KnowledgeLevel) {
+	e.state.KnowledgeLevel = level
+	e.state.LevelOverridden = true
+	e.applyLevelPreset(level)
+}
+
+// applyLevelPreset switches the client's verbosity and temperature to the level's preset,
+// unless the user changed them by hand this session. The configured values are left alone,
+// so a saved config keeps them.
+func (e *Engine) applyLevelPreset(level KnowledgeLevel) {
+	if e.state.PresetOverridden {
+		return
+	}
+	verbosity, temperature := e.config.ResponseVerbosity, e.config.Temperature
+	if preset, ok := e.config.LevelPresets[level]; ok {
+		verbosity, temperature = preset.Verbosity, preset.Temperature
+	}
+	e.openaiClient.Prompt.Verbosity = verbosity
+	e.openaiClient.Temperature = temperature
+}
+
+// SetVerbosity sets the reply length by hand, which stops level presets from changing it this session
+func (e *Engine) SetVerbosity(verbosity int) {
+	e.openaiClient.Prompt.Verbosity = verbosity
+	e.state.PresetOverridden = true
+}
+
+// SetStateless turns sending earlier turns as context off (true) or on (false)
+func (e *Engine) SetStateless(stateless bool) {
+	e.state.Stateless = stateless
+}
+
+// Stateless reports whether turns are answered without earlier context
+func (e *Engine) Stateless() bool {
+	return e.state.Stateless
+}
+
+// SetTopic sets the subject of the conversation the learner is told about
+func (e *Engine) SetTopic(topic string) {
+	e.state.Topic = strings.TrimSpace(topic)
+}
+
+// Topic returns the subject of the conversation, "general" when none is set
+func (e *Engine) Topic() string {
+	if e.state.Topic == "" {
+		return defaultTopic
+	}
+	return e.state.Topic
+}
+
+// defaultTopic is the topic of conversations without one set
+const defaultTopic = "general"
+
+// KnowledgeLevel returns the current knowledge level
+func (e *Engine) KnowledgeLevel() KnowledgeLevel {
+	return e.state.KnowledgeLevel
+}
+
+// History returns a copy of the conversation history, oldest first
+func (e *Engine) History() []ConversationEntry {
+	return append([]ConversationEntry(nil), e.state.ConversationLog...)
+}
+
+// ClearHistory forgets all previous turns
+func (e *Engine) ClearHistory() {
+	e.state.ConversationLog = e.state.ConversationLog[:0]
+	e.state.LastMessage = ""
+	e.state.LastResponse = ""
+	e.state.Title = ""
+	e.persistLog()
+}
+
+// State returns the shared conversation state
+func (e *Engine) State() *AppState {
+	return e.state
+}
+
+// ChatClient returns the client used for conversation requests
+func (e *Engine) ChatClient() *ChatClient {
+	return e.openaiClient
+}
+
+// TTSClient returns the text-to-speech client
+func (e *Engine) TTSClient() *TTSClient {
+	return e.ttsClient
+}
+
+// STTClient returns the speech-to-text client
+func (e *Engine) STTClient() *STTClient {
+	return e.sttClient
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jorkle/jork/internal/config"
+)
+
+func TestProcessVoiceFileEmptyTranscription(t *testing.T) {
+	for _, text := range []string{"", "  \n\t"} {
+		chatCalls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/audio/transcriptions":
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"text": ` + quoteJSON(text) + `}`))
+			default:
+				chatCalls++
+				http.Error(w, "unexpected request", http.StatusBadRequest)
+			}
+		}))
+
+		dir := t.TempDir()
+		cfg := config.DefaultConfig()
+		cfg.OpenAIAPIKey = "test-key"
+		cfg.OpenAIBaseURL = server.URL
+		cfg.HTTPProxy = ""
+		cfg.ConfigDir = dir
+		cfg.LogFile = ""
+		cfg.STTMaxRetries = 0
+		audioFile := filepath.Join(dir, "input.wav")
+		if err := os.WriteFile(audioFile, []byte("RIFF\x00\x00\x00\x00WAVE"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		e := New(cfg)
+		_, err := e.ProcessVoiceFile(context.Background(), audioFile)
+		server.Close()
+		if !errors.Is(err, ErrEmptyTranscription) {
+			t.Errorf("transcription %q: error = %v, want ErrEmptyTranscription", text, err)
+		}
+		if chatCalls != 0 {
+			t.Errorf("transcription %q: %d requests beyond the transcription, want none", text, chatCalls)
+		}
+		if n := len(e.State().ConversationLog); n != 0 {
+			t.Errorf("transcription %q: %d entries logged, want none", text, n)
+		}
+	}
+}
+
+// quoteJSON returns s as a JSON string literal
+func quoteJSON(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func TestSanitizeResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"plain", "Hello, world!", "Hello, world!"},
+		{"newlines and tabs kept", "one\ntwo\tthree", "one\ntwo\tthree"},
+		{"unicode kept", "café 👋 naïve", "café 👋 naïve"},
+		{"invalid bytes", "ab\xffcd\xc3", "ab�cd�"},
+		{"truncated rune", "price: 5\xe2\x82", "price: 5�"},
+		{"color", "\x1b[31mred\x1b[0m text", "red text"},
+		{"cursor movement", "top\x1b[2J\x1b[Hcleared", "topcleared"},
+		{"terminal title", "\x1b]0;pwned\x07hello", "hello"},
+		{"title with string terminator", "\x1b]2;pwned\x1b\\hello", "hello"},
+		{"lone escape", "a\x1bcb", "ab"},
+		{"control characters", "bell\a back\b null\x00 cr\r del\x7f", "bell back null cr del"},
+		{"C1 control", "a\u009b31mb", "a31mb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeResponse(tt.response)
+			if got != tt.want {
+				t.Errorf("sanitizeResponse(%q) = %q, want %q", tt.response, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("sanitizeResponse(%q) = %q is not valid UTF-8", tt.response, got)
+			}
+			if strings.ContainsRune(got, '\x1b') {
+				t.Errorf("sanitizeResponse(%q) = %q still holds an escape", tt.response, got)
+			}
+		})
+	}
+}
+
+func TestLevelPresetLeavesConfigAlone(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.OpenAIAPIKey = "test-key"
+	cfg.LogFile = ""
+	cfg.ResponseVerbosity = 3
+	cfg.Temperature = 0.7
+	cfg.LevelPresets = map[KnowledgeLevel]config.LevelPreset{CoWorker: {Verbosity: 5, Temperature: 0.2}}
+	e := New(cfg)
+
+	e.SetKnowledgeLevel(CoWorker)
+	if e.openaiClient.Prompt.Verbosity != 5 || e.openaiClient.Temperature != 0.2 {
+		t.Errorf("client verbosity %d, temperature %v, want the preset's 5, 0.2",
+			e.openaiClient.Prompt.Verbosity, e.openaiClient.Temperature)
+	}
+	e.SetKnowledgeLevel(Child)
+	if e.openaiClient.Prompt.Verbosity != 3 || e.openaiClient.Temperature != 0.7 {
+		t.Errorf("client verbosity %d, temperature %v, want the configured 3, 0.7",
+			e.openaiClient.Prompt.Verbosity, e.openaiClient.Temperature)
+	}
+	if cfg.ResponseVerbosity != 3 || cfg.Temperature != 0.7 {
+		t.Errorf("config changed to verbosity %d, temperature %v", cfg.ResponseVerbosity, cfg.Temperature)
+	}
+}
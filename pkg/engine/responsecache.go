@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jorkle/jork/internal/ai"
+)
+
+// Replies are cached under ConfigDir/cache, one JSON file per input, model, level, mode and
+// topic, when Config.EnableCache is on. The history isn't part of the key, so a repeated
+// prompt gets the same reply whatever came before it; that is the point when demoing.
+
+// cachedResponse is a reply stored in the response cache
+type cachedResponse struct {
+	Text     string    `json:"text"`
+	Model    string    `json:"model"`
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// noCacheKey marks a context whose turn must not be answered from the cache
+type noCacheKey struct{}
+
+// WithoutCache returns a context for a turn that always asks the model, refreshing its cache entry
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// cacheAllowed reports whether the turn run with ctx may be answered from the cache
+func (e *Engine) cacheAllowed(ctx context.Context) bool {
+	return e.config.EnableCache && ctx.Value(noCacheKey{}) == nil
+}
+
+// responseCachePath returns the cache file for a reply to input in the current conversation settings
+func (e *Engine) responseCachePath(input string) string {
+	key, _ := json.Marshal([]any{input, e.openaiClient.Model, e.state.KnowledgeLevel, e.state.CurrentMode, e.Topic()})
+	sum := sha256.Sum256(key)
+	return filepath.Join(e.config.ConfigDir, "cache", hex.EncodeToString(sum[:])+".json")
+}
+
+// lookupResponse returns the cached reply to input, if there is one younger than CacheTTLHours
+func (e *Engine) lookupResponse(input string) (ai.ChatResult, bool) {
+	data, err := os.ReadFile(e.responseCachePath(input))
+	if err != nil {
+		return ai.ChatResult{}, false
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return ai.ChatResult{}, false
+	}
+	if time.Since(cached.StoredAt) > time.Duration(e.config.CacheTTLHours)*time.Hour {
+		return ai.ChatResult{}, false
+	}
+	return ai.ChatResult{Text: cached.Text, Model: cached.Model}, true
+}
+
+// storeResponse caches result as the reply to input
+func (e *Engine) storeResponse(input string, result ai.ChatResult) {
+	if err := e.writeResponseCache(input, result); err != nil {
+		log.Printf("Error caching response: %v", err)
+	}
+}
+
+func (e *Engine) writeResponseCache(input string, result ai.ChatResult) error {
+	data, err := json.Marshal(cachedResponse{Text: result.Text, Model: result.Model, StoredAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to encode cached response: %w", err)
+	}
+	path := e.responseCachePath(input)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create response cache directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+#!/bin/sh
+# Builds bin/jork with the version and commit it was built from, as reported by --version
+set -e
+
+version=$(git describe --tags --always --dirty 2>/dev/null || echo dev)
+commit=$(git rev-parse --short HEAD 2>/dev/null || true)
+
+go build -ldflags "-X main.version=$version -X main.commit=$commit" -o bin/jork ./cmd/jork